@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/zgsm-ai/client-manager/internal"
+	"github.com/zgsm-ai/client-manager/models"
+	"github.com/zgsm-ai/client-manager/services"
+)
+
+/**
+ * AuditController handles HTTP requests for the audit trail
+ * @description
+ * - Exposes audit entries to administrators
+ * - Integrates with AuditService for business logic
+ */
+type AuditController struct {
+	auditService *services.AuditService
+	log          *logrus.Logger
+}
+
+/**
+ * NewAuditController creates a new AuditController instance
+ * @param {logrus.Logger} log - Logger instance
+ * @param {*services.AuditService} auditService - Audit service
+ * @returns {*AuditController} New AuditController instance
+ */
+func NewAuditController(log *logrus.Logger, auditService *services.AuditService) *AuditController {
+	return &AuditController{
+		auditService: auditService,
+		log:          log,
+	}
+}
+
+// ListAuditEntries handles GET /audit request
+// @Summary List audit entries
+// @Description Retrieve the audit trail of write operations, newest first (admin only)
+// @Tags Audit
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param resource query string false "Resource filter (e.g. log)"
+// @Param actor query string false "Actor filter"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Number of items per page, defaults to pagination.audit.default and is capped at pagination.audit.max" default(20)
+// @Param fields query string false "Comma separated fields to return, e.g. id,actor,action"
+// @Param count query bool false "Count matching entries, false returns a null total" default(true)
+// @Param approximate_total query bool false "Accept a total cached for up to database.count_cache_ttl" default(false)
+// @Success 200 {object} map[string]interface{} "Audit entries with pagination"
+// @Failure 400 {object} map[string]interface{} "Invalid parameters"
+// @Failure 401 {object} map[string]interface{} "Missing or invalid admin key"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /client-manager/api/v1/audit [get]
+func (ac *AuditController) ListAuditEntries(c *gin.Context) {
+	// Record start time for metrics
+	start := time.Now()
+
+	var args services.ListAuditArgs
+	if err := c.ShouldBindQuery(&args); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    "argument.invalid",
+			"message": internal.LocalizeError(c.GetHeader("Accept-Language"), err),
+		})
+		return
+	}
+	fields, err := parseFields(c, models.AuditEntry{})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    "argument.invalid",
+			"message": internal.LocalizeError(c.GetHeader("Accept-Language"), err),
+		})
+		return
+	}
+
+	entries, paging, err := ac.auditService.ListAuditEntries(c.Request.Context(), &args)
+	if err != nil {
+		respondError(c, ac.log, err)
+		return
+	}
+	data, err := projectFields(entries, fields)
+	if err != nil {
+		respondError(c, ac.log, err)
+		return
+	}
+
+	// Record successful audit listing metrics
+	duration := time.Since(start)
+	internal.RecordHTTPRequest("GET", "/client-manager/api/v1/audit", http.StatusOK, duration)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    "success",
+		"message": "Audit entries retrieved successfully",
+		"data":    data,
+		"paging":  paging,
+	})
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/zgsm-ai/client-manager/internal"
+	"github.com/zgsm-ai/client-manager/services"
+)
+
+/**
+ * respondError writes the standard error response for a service error
+ * @param {*gin.Context} c - Gin context
+ * @param {*logrus.Logger} log - Logger instance
+ * @param {error} err - Error to handle
+ * @description
+ * - Maps service error types to HTTP status codes
+ * - Returns the standard {code, message} error envelope
+ * - Lists every invalid field of ValidationErrors under fields
+ * - Localizes the message according to Accept-Language, codes stay stable
+ * - Maps an exceeded request deadline to 504 and an exceeded body limit to 413
+ * - Hides details of unexpected errors behind internal.error
+ */
+func respondError(c *gin.Context, log *logrus.Logger, err error) {
+	// Log error
+	log.WithError(err).Error("Request processing failed")
+
+	lang := c.GetHeader("Accept-Language")
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		internal.GatewayTimeout(c)
+		return
+	}
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		internal.RequestTooLarge(c, maxErr.Limit)
+		return
+	}
+
+	// Handle different error types
+	switch e := err.(type) {
+	case *services.ValidationError:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    "validation.error",
+			"message": internal.Localize(lang, e.Message),
+			"field":   e.Field,
+			"rule":    e.Rule,
+		})
+	case services.ValidationErrors:
+		// The first error keeps the single-field shape, fields lists all of them
+		fields := make([]gin.H, len(e))
+		for i, fe := range e {
+			fields[i] = gin.H{
+				"field":   fe.Field,
+				"message": internal.Localize(lang, fe.Message),
+				"rule":    fe.Rule,
+			}
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    "validation.error",
+			"message": fields[0]["message"],
+			"field":   fields[0]["field"],
+			"rule":    fields[0]["rule"],
+			"fields":  fields,
+		})
+	case *services.ArgumentError:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    "argument.invalid",
+			"message": internal.LocalizeError(lang, e.Err),
+		})
+	case *services.UnauthorizedError:
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"code":    "auth.unauthorized",
+			"message": internal.Localize(lang, e.Message),
+		})
+	case *services.ForbiddenError:
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    "auth.forbidden",
+			"message": internal.Localize(lang, e.Message),
+		})
+	case *services.StorageError:
+		if e.Unavailable {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"code":    "storage.unavailable",
+				"message": internal.Localize(lang, e.Message),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    "storage.error",
+			"message": internal.Localize(lang, e.Message),
+		})
+	case *services.ConflictError:
+		c.JSON(http.StatusConflict, gin.H{
+			"code":    "conflict.error",
+			"message": internal.Localize(lang, e.Message),
+		})
+	case *services.PreconditionFailedError:
+		if e.ETag != "" {
+			c.Header("ETag", e.ETag)
+		}
+		c.JSON(http.StatusPreconditionFailed, gin.H{
+			"code":    "precondition.failed",
+			"message": internal.Localize(lang, e.Message),
+		})
+	case *services.NotFoundError:
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    "notfound.error",
+			"message": internal.Localize(lang, e.Message),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    "internal.error",
+			"message": internal.Localize(lang, internal.NewMessage(internal.MsgInternalError)),
+		})
+	}
+}
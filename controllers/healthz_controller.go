@@ -8,6 +8,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 
+	"github.com/zgsm-ai/client-manager/internal"
 	"github.com/zgsm-ai/client-manager/utils"
 )
 
@@ -128,7 +129,7 @@ func (hc *HealthController) LiveHandler(c *gin.Context) {
 
 // ReadyHandler handles GET /ready request
 // @Summary Readiness check endpoint
-// @Description Check if the service is ready to accept traffic
+// @Description Check if the service is ready to accept traffic: the database answers and the upload directory is writable
 // @Tags Health
 // @Accept json
 // @Produce json
@@ -136,17 +137,33 @@ func (hc *HealthController) LiveHandler(c *gin.Context) {
 // @Failure 503 {object} map[string]interface{} "Service not ready"
 // @Router /ready [get]
 func (hc *HealthController) ReadyHandler(c *gin.Context) {
-	// Check if service is ready
-	// This can be extended to check database connections, external services, etc.
+	// Check every component the service depends on
+	checks := map[string]func() error{
+		"database": internal.CheckDatabase,
+		"upload_dir": func() error {
+			return internal.CheckDirWritable(internal.GetStorageConfig().BaseDir)
+		},
+	}
 	isReady := true
+	components := make(map[string]interface{}, len(checks))
+	for name, check := range checks {
+		if err := check(); err != nil {
+			isReady = false
+			hc.log.WithError(err).WithField("component", name).Warn("Readiness check failed")
+			components[name] = map[string]interface{}{"status": "error", "error": err.Error()}
+			continue
+		}
+		components[name] = map[string]interface{}{"status": "ok"}
+	}
 
 	if isReady {
 		c.JSON(http.StatusOK, gin.H{
 			"code":    "success",
 			"message": "Service is ready",
 			"data": map[string]interface{}{
-				"status":    "ready",
-				"timestamp": time.Now().Format(time.RFC3339),
+				"status":     "ready",
+				"timestamp":  time.Now().Format(time.RFC3339),
+				"components": components,
 			},
 		})
 	} else {
@@ -154,9 +171,89 @@ func (hc *HealthController) ReadyHandler(c *gin.Context) {
 			"code":    "service.unavailable",
 			"message": "Service is not ready",
 			"data": map[string]interface{}{
-				"status":    "not_ready",
-				"timestamp": time.Now().Format(time.RFC3339),
+				"status":     "not_ready",
+				"timestamp":  time.Now().Format(time.RFC3339),
+				"components": components,
 			},
 		})
 	}
 }
+
+// GetRuntimeStats handles GET /stats/runtime request
+// @Summary Go runtime statistics
+// @Description Report goroutine, heap and GC statistics of the process
+// @Tags Health
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Runtime statistics"
+// @Router /stats/runtime [get]
+func (hc *HealthController) GetRuntimeStats(c *gin.Context) {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	var lastGC string
+	if memStats.LastGC > 0 {
+		lastGC = time.Unix(0, int64(memStats.LastGC)).Format(time.RFC3339)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    "success",
+		"message": "Runtime statistics retrieved successfully",
+		"data": map[string]interface{}{
+			"goroutines": runtime.NumGoroutine(),
+			"num_cpu":    runtime.NumCPU(),
+			"go_version": runtime.Version(),
+			"heap": map[string]interface{}{
+				"alloc":    memStats.HeapAlloc,
+				"sys":      memStats.HeapSys,
+				"idle":     memStats.HeapIdle,
+				"in_use":   memStats.HeapInuse,
+				"objects":  memStats.HeapObjects,
+				"released": memStats.HeapReleased,
+			},
+			"gc": map[string]interface{}{
+				"num_gc":          memStats.NumGC,
+				"pause_total_ns":  memStats.PauseTotalNs,
+				"last_gc":         lastGC,
+				"next_gc":         memStats.NextGC,
+				"gc_cpu_fraction": memStats.GCCPUFraction,
+			},
+		},
+	})
+}
+
+// GetAdminStats handles GET /admin/stats request
+// @Summary Request statistics
+// @Description Report uptime and request/error totals since startup, requires the admin API key
+// @Tags Health
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Success 200 {object} map[string]interface{} "Uptime, request and error totals"
+// @Failure 401 {object} map[string]interface{} "Missing or invalid admin key"
+// @Router /admin/stats [get]
+func (hc *HealthController) GetAdminStats(c *gin.Context) {
+	var uptime time.Duration
+	if startupTime := utils.GetStartupTime(); !startupTime.IsZero() {
+		uptime = time.Since(startupTime)
+	}
+
+	requestCount := utils.GetRequestCount()
+	errorCount := utils.GetErrorCount()
+	var errorRate float64
+	if requestCount > 0 {
+		errorRate = float64(errorCount) / float64(requestCount)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    "success",
+		"message": "Statistics retrieved successfully",
+		"data": map[string]interface{}{
+			"uptime":         uptime.String(),
+			"uptime_seconds": uptime.Seconds(),
+			"total_requests": requestCount,
+			"total_errors":   errorCount,
+			"error_rate":     errorRate,
+		},
+	})
+}
@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/zgsm-ai/client-manager/internal"
+	"github.com/zgsm-ai/client-manager/internal/testutil"
+	"github.com/zgsm-ai/client-manager/utils"
+)
+
+// serveHealth calls a HealthController handler and decodes the data of its response
+func serveHealth(t *testing.T, handler func(hc *HealthController) gin.HandlerFunc) (int, map[string]interface{}) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	log := testutil.NewLogger()
+	r := gin.New()
+	r.GET("/", handler(NewHealthController(log)))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode %s: %v", w.Body.String(), err)
+	}
+	return w.Code, resp.Data
+}
+
+func TestGetRuntimeStats(t *testing.T) {
+	status, data := serveHealth(t, func(hc *HealthController) gin.HandlerFunc { return hc.GetRuntimeStats })
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+
+	heap, _ := data["heap"].(map[string]interface{})
+	gc, _ := data["gc"].(map[string]interface{})
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{"goroutines", data["goroutines"]},
+		{"num_cpu", data["num_cpu"]},
+		{"heap.alloc", heap["alloc"]},
+		{"heap.sys", heap["sys"]},
+		{"gc.next_gc", gc["next_gc"]},
+	}
+	for _, tt := range tests {
+		if n, ok := tt.value.(float64); !ok || n <= 0 {
+			t.Errorf("%s = %v, want a positive number", tt.name, tt.value)
+		}
+	}
+	if v, _ := data["go_version"].(string); v == "" {
+		t.Errorf("go_version is empty")
+	}
+}
+
+func TestGoRuntimeMetricsRegistered(t *testing.T) {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]bool{}
+	for _, family := range families {
+		found[family.GetName()] = true
+	}
+	for _, name := range []string{"go_goroutines", "go_memstats_heap_alloc_bytes"} {
+		if !found[name] {
+			t.Errorf("metric %s is not registered", name)
+		}
+	}
+}
+
+// readOnlyDir returns a directory files cannot be created in, skipping the test
+// when directory permissions are not enforced, e.g. when running as root
+func readOnlyDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(dir, 0755) })
+	if f, err := os.CreateTemp(dir, "probe-*"); err == nil {
+		f.Close()
+		t.Skip("directory permissions are not enforced for this user")
+	}
+	return dir
+}
+
+func TestReadyHandler(t *testing.T) {
+	db := testutil.NewDB(t, nil)
+	previous := internal.DB
+	internal.DB = db
+	t.Cleanup(func() { internal.DB = previous })
+
+	tests := []struct {
+		name      string
+		uploadDir func(t *testing.T) string
+		status    int
+		uploadOK  bool
+	}{
+		{"writable upload dir", func(t *testing.T) string { return t.TempDir() }, http.StatusOK, true},
+		{"read-only upload dir", readOnlyDir, http.StatusServiceUnavailable, false},
+		{
+			name: "upload dir is a file",
+			uploadDir: func(t *testing.T) string {
+				path := filepath.Join(t.TempDir(), "file")
+				if err := os.WriteFile(path, nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return path
+			},
+			status: http.StatusServiceUnavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.SetConfig(t, "storage.base_dir", tt.uploadDir(t))
+			status, data := serveHealth(t, func(hc *HealthController) gin.HandlerFunc { return hc.ReadyHandler })
+			if status != tt.status {
+				t.Errorf("status = %d, want %d", status, tt.status)
+			}
+			components, _ := data["components"].(map[string]interface{})
+			database, _ := components["database"].(map[string]interface{})
+			uploadDir, _ := components["upload_dir"].(map[string]interface{})
+			if database["status"] != "ok" {
+				t.Errorf("database component = %v, want ok", database)
+			}
+			if got := uploadDir["status"] == "ok"; got != tt.uploadOK {
+				t.Errorf("upload_dir component = %v, want ok %v", uploadDir, tt.uploadOK)
+			}
+		})
+	}
+}
+
+func TestGetAdminStats(t *testing.T) {
+	prev := utils.GetStartupTime()
+	utils.SetStartupTime(time.Now().Add(-time.Minute))
+	t.Cleanup(func() { utils.SetStartupTime(prev) })
+	// The counters are process wide, only the requests added here are known
+	before := utils.GetRequestCount()
+	for i := 0; i < 4; i++ {
+		utils.IncrementRequestCount()
+	}
+	utils.IncrementErrorCount()
+
+	status, data := serveHealth(t, func(hc *HealthController) gin.HandlerFunc { return hc.GetAdminStats })
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	num := func(key string) float64 { n, _ := data[key].(float64); return n }
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"uptime is set", data["uptime"] != nil && data["uptime"] != ""},
+		{"uptime_seconds covers the startup time", num("uptime_seconds") >= 60},
+		{"total_requests includes the new requests", num("total_requests") >= float64(before+4)},
+		{"total_errors counts the error", num("total_errors") >= 1},
+		{"error_rate is a fraction", num("error_rate") > 0 && num("error_rate") <= 1},
+	}
+	for _, tt := range tests {
+		if !tt.ok {
+			t.Errorf("%s: got %v", tt.name, data)
+		}
+	}
+}
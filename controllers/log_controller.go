@@ -1,21 +1,18 @@
 package controllers
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/sirupsen/logrus"
 
 	"github.com/zgsm-ai/client-manager/internal"
+	"github.com/zgsm-ai/client-manager/models"
 	"github.com/zgsm-ai/client-manager/services"
 )
 
@@ -71,33 +68,36 @@ func getUserId(header http.Header) string {
 		tokenString = authHeader[7:] // Remove "Bearer " prefix
 	}
 
-	// Parse token without verification (for now)
-	token, _, err := jwt.NewParser().ParseUnverified(tokenString, jwt.MapClaims{})
+	// Parse token, the signature is verified when a JWKS URL is configured
+	claims, err := internal.ParseTokenClaims(tokenString)
 	if err != nil {
 		return ""
 	}
 
-	// Extract claims
-	if claims, ok := token.Claims.(jwt.MapClaims); ok {
-		// Extract user_id from claims
-		if userID, exists := claims["id"]; exists {
-			// Set user_id in request header
-			return toString(userID)
-		}
+	// Extract user_id from claims
+	if userID, exists := claims["id"]; exists {
+		return toString(userID)
 	}
 	return ""
 }
 
 // PostLog handles POST /logs request
 // @Summary Create log
-// @Description Create a new log record
+// @Description Create a new log record, content already stored for the user is not written again and the response has duplicate set
 // @Tags Log
 // @Accept json
 // @Produce json
 // @Param log body map[string]interface{} true "Log data"
+// @Param If-Match header string false "ETag of the log from a previous upload, the append only applies if it is still current; * requires an existing log"
 // @Success 201 {object} map[string]interface{} "Created log"
+// @Success 200 {object} map[string]interface{} "Updated existing log"
 // @Failure 400 {object} map[string]interface{} "Invalid parameters"
+// @Failure 401 {object} map[string]interface{} "Missing or invalid token"
+// @Failure 403 {object} map[string]interface{} "user_id does not match the token"
+// @Failure 412 {object} map[string]interface{} "If-Match does not match the current log"
+// @Failure 413 {object} map[string]interface{} "Request body too large"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Failure 503 {object} map[string]interface{} "Log storage is unavailable"
 // @Router /client-manager/api/v1/logs [post]
 func (lc *LogController) PostLog(c *gin.Context) {
 	// Record start time for metrics
@@ -107,7 +107,14 @@ func (lc *LogController) PostLog(c *gin.Context) {
 	file, fileHead, err := c.Request.FormFile("logfile")
 	if err != nil {
 		lc.log.Errorf("get FormFile('logfile') error: %s", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			internal.RecordLogUploadRejected(internal.UploadRejectTooLarge)
+			lc.handleError(c, err)
+			return
+		}
+		internal.RecordLogUploadRejected(internal.UploadRejectBadRequest)
+		lc.handleError(c, &services.ArgumentError{Err: err})
 		return
 	}
 	defer file.Close()
@@ -115,52 +122,114 @@ func (lc *LogController) PostLog(c *gin.Context) {
 	s := c.Request.FormValue("args")
 	if err := json.Unmarshal([]byte(s), &args); err != nil {
 		lc.log.Errorf("get FormValue('args') error: %s", err.Error())
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		internal.RecordLogUploadRejected(internal.UploadRejectBadRequest)
+		lc.handleError(c, &services.ArgumentError{Err: err})
 		return
 	}
 	userId := getUserId(c.Request.Header)
+	if userId == "" {
+		lc.log.Errorf("validate token error: missing or invalid token, args.user_id: %s", args.UserID)
+		internal.RecordLogUploadRejected(internal.UploadRejectUnauthorized)
+		lc.handleError(c, &services.UnauthorizedError{Message: internal.NewMessage(internal.MsgBearerTokenRequired)})
+		return
+	}
 	if userId != args.UserID {
 		lc.log.Errorf("validate user_id error: args.user_id: %s, token.user_id: %s", args.UserID, userId)
-		c.JSON(http.StatusForbidden, gin.H{"error": "userID is invalid"})
+		internal.RecordLogUploadRejected(internal.UploadRejectForbidden)
+		lc.handleError(c, &services.ForbiddenError{Message: internal.NewMessage(internal.MsgUserIDMismatch)})
+		return
+	}
+
+	args.SizeBytes = fileHead.Size
+	// Report every invalid field at once, the file is stored under args.FileName
+	var validationErrs services.ValidationErrors
+	var ifMatchErr error
+	args.IfMatch, args.IfMatchAny, ifMatchErr = services.ParseIfMatch(c.GetHeader("If-Match"))
+	for _, err := range []error{ifMatchErr, lc.logService.ValidateUpload(&args)} {
+		if err := validationErrs.Add(err); err != nil {
+			lc.handleError(c, err)
+			return
+		}
+	}
+	if err := validationErrs.Err(); err != nil {
+		lc.log.Errorf("validate upload error: %s, client_id: %q, file_name: %q", err.Error(), args.ClientID, args.FileName)
+		internal.RecordLogUploadRejected(internal.UploadRejectInvalid)
+		lc.handleError(c, err)
+		return
+	}
+
+	if err := lc.logService.CheckStorage(); err != nil {
+		internal.RecordLogUploadRejected(internal.UploadRejectUnavailable)
+		lc.handleError(c, err)
 		return
 	}
 
 	// Record logs received metrics
 	internal.RecordLogsReceived(args.ClientID, "upload")
 
-	if _, err := lc.logService.CreateLog(context.Background(), &args); err != nil {
+	args.LineCount, args.Compressed, err = lc.logService.CountLines(file, args.FileName)
+	if err != nil {
+		lc.log.Errorf("inspect logfile error: %s, file_name: %q", err.Error(), args.FileName)
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			internal.RecordLogUploadRejected(internal.UploadRejectInvalid)
+		}
 		lc.handleError(c, err)
 		return
 	}
 
-	destPath := filepath.Join("/data", args.ClientID, fileHead.Filename)
-	if err := os.MkdirAll(filepath.Join("/data", args.ClientID), 0755); err != nil {
-		lc.log.Errorf("Failed to create file: %s, error: %s", destPath, err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create file"})
+	// Identical content already stored for the user is not written again
+	args.ContentHash, err = lc.logService.HashContent(file)
+	if err != nil {
+		lc.log.Errorf("Failed to read uploaded file: %s", err.Error())
+		lc.handleError(c, &services.StorageError{Message: internal.NewMessage(internal.MsgFileReadFailed), Err: err})
 		return
 	}
-	destFile, err := os.Create(destPath)
+	// The file is written before the record is committed and moved into place after it
+	saved, err := lc.logService.SaveLog(c.Request.Context(), &args, file)
 	if err != nil {
-		lc.log.Errorf("Failed to create file: %s, error: %s", destPath, err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create file"})
+		var validationErr *services.ValidationError
+		var validationErrs services.ValidationErrors
+		var precondErr *services.PreconditionFailedError
+		switch {
+		case errors.As(err, &validationErr), errors.As(err, &validationErrs):
+			internal.RecordLogUploadRejected(internal.UploadRejectInvalid)
+		case errors.As(err, &precondErr):
+			internal.RecordLogUploadRejected(internal.UploadRejectPrecondition)
+		}
+		lc.handleError(c, err)
 		return
 	}
-	defer destFile.Close()
-	// 将上传的文件内容复制到目标文件
-	if _, err := io.Copy(destFile, file); err != nil {
-		lc.log.Errorf("Failed to save file: %s, error: %s", destPath, err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
-		return
+
+	destPath := lc.logService.StoragePath(saved.Log.ClientID, saved.Log.FileName)
+	internal.RecordLogUpload(saved.Written)
+	message := fmt.Sprintf("File uploaded successfully: %s", destPath)
+	if saved.Duplicate {
+		lc.log.WithFields(logrus.Fields{
+			"client_id":    args.ClientID,
+			"user_id":      args.UserID,
+			"file_name":    args.FileName,
+			"content_hash": args.ContentHash,
+		}).Info("Stored duplicate log upload without writing it again")
+		message = fmt.Sprintf("File already uploaded: %s", destPath)
+	}
+
+	status := http.StatusOK
+	if saved.Created {
+		status = http.StatusCreated
 	}
 
 	// Record successful log upload metrics
 	duration := time.Since(start)
-	internal.RecordHTTPRequest("POST", "/client-manager/api/v1/logs", http.StatusOK, duration)
+	internal.RecordHTTPRequest("POST", "/client-manager/api/v1/logs", status, duration)
 
 	// 返回成功响应
-	c.JSON(http.StatusOK, gin.H{
-		"code":    "success",
-		"message": fmt.Sprintf("File uploaded successfully: %s", destPath),
+	c.Header("ETag", services.LogETag(saved.Log))
+	c.JSON(status, gin.H{
+		"code":      "success",
+		"message":   message,
+		"created":   saved.Created,
+		"duplicate": saved.Duplicate,
 	})
 }
 
@@ -181,33 +250,184 @@ func (lc *LogController) GetLogs(c *gin.Context) {
 	// Record start time for metrics
 	start := time.Now()
 
-	clientID := c.Param("client_id")
+	// Rejected IDs must not become client_id label values
+	clientID, err := lc.logService.NormalizeClientID(c.Param("client_id"))
+	if err != nil {
+		lc.handleError(c, err)
+		return
+	}
 	fileName := c.Param("file_name")
 
-	// Record logs received metrics for retrieval
-	internal.RecordLogsReceived(clientID, "retrieve")
-
 	filePath, err := lc.logService.GetLogs(c.Request.Context(), clientID, fileName)
 	if err != nil {
 		lc.handleError(c, err)
 		return
 	}
 
+	// Record logs received metrics for retrieval
+	internal.RecordLogsReceived(clientID, "retrieve")
+
 	// Record successful log retrieval metrics
 	duration := time.Since(start)
-	internal.RecordHTTPRequest("GET", "/client-manager/api/v1/logs/"+clientID+"/"+fileName, http.StatusOK, duration)
+	internal.RecordHTTPRequest("GET", "/client-manager/api/v1/logs/:client_id/:file_name", http.StatusOK, duration)
 
 	c.File(filePath)
 }
 
+// GetClientSummary handles GET /clients/{client_id}/logs/summary request
+// @Summary Get client log summary
+// @Description Retrieve the number of files, stored bytes and upload date range of a client
+// @Tags Log
+// @Accept json
+// @Produce json
+// @Param client_id path string true "Client ID"
+// @Param fields query string false "Comma separated fields to return, e.g. total_files,total_bytes"
+// @Success 200 {object} map[string]interface{} "Client log summary"
+// @Failure 400 {object} map[string]interface{} "Invalid parameters"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /client-manager/api/v1/clients/{client_id}/logs/summary [get]
+func (lc *LogController) GetClientSummary(c *gin.Context) {
+	// Record start time for metrics
+	start := time.Now()
+
+	fields, err := parseFields(c, models.ClientLogSummary{})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    "argument.invalid",
+			"message": internal.LocalizeError(c.GetHeader("Accept-Language"), err),
+		})
+		return
+	}
+
+	summary, err := lc.logService.GetClientSummary(c.Request.Context(), c.Param("client_id"))
+	if err != nil {
+		lc.handleError(c, err)
+		return
+	}
+	data, err := projectFields(summary, fields)
+	if err != nil {
+		lc.handleError(c, err)
+		return
+	}
+
+	// Record successful summary metrics
+	duration := time.Since(start)
+	internal.RecordHTTPRequest("GET", "/client-manager/api/v1/clients/:client_id/logs/summary", http.StatusOK, duration)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    "success",
+		"message": "Client log summary retrieved successfully",
+		"data":    data,
+	})
+}
+
+// GetLogStats handles GET /logs/stats request
+// @Summary Get log statistics
+// @Description Retrieve daily log counts, per-client totals and the number of active clients for a date range
+// @Tags Log
+// @Accept json
+// @Produce json,text/csv
+// @Param start_date query string false "Start date (YYYY-MM-DD), defaults to 7 days up to end_date"
+// @Param end_date query string false "End date (YYYY-MM-DD), inclusive, defaults to today"
+// @Param format query string false "Response format (json or csv), overrides the Accept header; CSV lists the daily counts"
+// @Success 200 {object} services.LogStats "Log statistics"
+// @Failure 400 {object} map[string]interface{} "Invalid parameters"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /client-manager/api/v1/logs/stats [get]
+func (lc *LogController) GetLogStats(c *gin.Context) {
+	// Record start time for metrics
+	start := time.Now()
+
+	var args services.LogStatsArgs
+	if err := c.ShouldBindQuery(&args); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    "argument.invalid",
+			"message": internal.LocalizeError(c.GetHeader("Accept-Language"), err),
+		})
+		return
+	}
+	format, err := negotiateFormat(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    "argument.invalid",
+			"message": internal.LocalizeError(c.GetHeader("Accept-Language"), err),
+		})
+		return
+	}
+
+	stats, err := lc.logService.GetLogStats(c.Request.Context(), &args)
+	if err != nil {
+		lc.handleError(c, err)
+		return
+	}
+
+	// Record successful log stats metrics
+	duration := time.Since(start)
+	internal.RecordHTTPRequest("GET", "/client-manager/api/v1/logs/stats", http.StatusOK, duration)
+
+	if format == formatCSV {
+		// Rows are the daily counts, the range and totals travel in headers
+		c.Header("X-Start-Date", stats.StartDate)
+		c.Header("X-End-Date", stats.EndDate)
+		c.Header("X-Total-Count", fmt.Sprintf("%d", stats.TotalLogs))
+		c.Header("X-Active-Clients", fmt.Sprintf("%d", stats.ActiveClients))
+		renderCSV(c, http.StatusOK, stats.Daily, nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    "success",
+		"message": "Log statistics retrieved successfully",
+		"data":    stats,
+	})
+}
+
+// ListModules handles GET /logs/modules request
+// @Summary List log modules
+// @Description Retrieve the distinct module names of uploaded logs with their number of logs
+// @Tags Log
+// @Accept json
+// @Produce json
+// @Param client_id query string false "Only count the logs of this client"
+// @Success 200 {array} models.ModuleLogCount "Modules ordered by name"
+// @Failure 400 {object} map[string]interface{} "Invalid parameters"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /client-manager/api/v1/logs/modules [get]
+func (lc *LogController) ListModules(c *gin.Context) {
+	// Record start time for metrics
+	start := time.Now()
+
+	modules, err := lc.logService.ListModules(c.Request.Context(), c.Query("client_id"))
+	if err != nil {
+		lc.handleError(c, err)
+		return
+	}
+
+	// Record successful module listing metrics
+	duration := time.Since(start)
+	internal.RecordHTTPRequest("GET", "/client-manager/api/v1/logs/modules", http.StatusOK, duration)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    "success",
+		"message": "Log modules retrieved successfully",
+		"data":    modules,
+	})
+}
+
 // ListLogs handles GET /logs request
 // @Summary Get log statistics
 // @Description Retrieve log statistics for a given time period
 // @Tags Log
 // @Accept json
-// @Produce json
+// @Produce json,text/csv
 // @Param start_date query string true "Start date (YYYY-MM-DD)"
 // @Param end_date query string true "End date (YYYY-MM-DD)"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Number of items per page, defaults to pagination.log.default and is capped at pagination.log.max" default(10)
+// @Param format query string false "Response format (json or csv), overrides the Accept header"
+// @Param fields query string false "Comma separated fields to return, e.g. id,client_id,file_name"
+// @Param count query bool false "Count matching logs, false returns a null total and is faster on large tables" default(true)
+// @Param approximate_total query bool false "Accept a total cached for up to database.count_cache_ttl" default(false)
 // @Success 200 {object} map[string]interface{} "Log statistics"
 // @Failure 400 {object} map[string]interface{} "Invalid parameters"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
@@ -221,14 +441,25 @@ func (lc *LogController) ListLogs(c *gin.Context) {
 	if err := c.ShouldBindQuery(&args); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"code":    "argument.invalid",
-			"message": err.Error(),
+			"message": internal.LocalizeError(c.GetHeader("Accept-Language"), err),
 		})
 		return
 	}
-
-	// Record logs received metrics for listing
-	if args.ClientId != "" {
-		internal.RecordLogsReceived(args.ClientId, "list")
+	format, err := negotiateFormat(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    "argument.invalid",
+			"message": internal.LocalizeError(c.GetHeader("Accept-Language"), err),
+		})
+		return
+	}
+	fields, err := parseFields(c, models.Log{})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    "argument.invalid",
+			"message": internal.LocalizeError(c.GetHeader("Accept-Language"), err),
+		})
+		return
 	}
 
 	// Get log statistics
@@ -238,15 +469,35 @@ func (lc *LogController) ListLogs(c *gin.Context) {
 		return
 	}
 
+	// Record logs received metrics for listing, the client ID is normalized by now
+	if args.ClientId != "" {
+		internal.RecordLogsReceived(args.ClientId, "list")
+	}
+
 	// Record successful log listing metrics
 	duration := time.Since(start)
 	internal.RecordHTTPRequest("GET", "/client-manager/api/v1/logs", http.StatusOK, duration)
 
+	if format == formatCSV {
+		if paging.Total != nil {
+			c.Header("X-Total-Count", fmt.Sprintf("%d", *paging.Total))
+			c.Header("X-Total-Pages", fmt.Sprintf("%d", *paging.TotalPages))
+		}
+		renderCSV(c, http.StatusOK, logs, fields)
+		return
+	}
+
+	data, err := projectFields(logs, fields)
+	if err != nil {
+		lc.handleError(c, err)
+		return
+	}
+
 	// Return success response
 	c.JSON(http.StatusOK, gin.H{
 		"code":    "success",
 		"message": "Log statistics retrieved successfully",
-		"data":    logs,
+		"data":    data,
 		"paging":  paging,
 	})
 }
@@ -261,31 +512,5 @@ func (lc *LogController) ListLogs(c *gin.Context) {
  * - Logs errors for debugging
  */
 func (lc *LogController) handleError(c *gin.Context, err error) {
-	// Log error
-	lc.log.WithError(err).Error("Request processing failed")
-
-	// Handle different error types
-	switch e := err.(type) {
-	case *services.ValidationError:
-		c.JSON(http.StatusBadRequest, gin.H{
-			"code":    "validation.error",
-			"message": e.Message,
-			"field":   e.Field,
-		})
-	case *services.ConflictError:
-		c.JSON(http.StatusConflict, gin.H{
-			"code":    "conflict.error",
-			"message": e.Message,
-		})
-	case *services.NotFoundError:
-		c.JSON(http.StatusNotFound, gin.H{
-			"code":    "notfound.error",
-			"message": e.Message,
-		})
-	default:
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    "internal.error",
-			"message": "Internal server error",
-		})
-	}
+	respondError(c, lc.log, err)
 }
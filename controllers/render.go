@@ -0,0 +1,291 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zgsm-ai/client-manager/internal"
+)
+
+const (
+	formatJSON = "json"
+	formatCSV  = "csv"
+
+	mimeCSV = "text/csv"
+)
+
+/**
+ * negotiateFormat determines the response format requested by the client
+ * @param {*gin.Context} c - Gin context
+ * @returns {string, error} Response format ("json" or "csv") and error if the format is unsupported
+ * @description
+ * - The explicit `format` query parameter takes precedence
+ * - Falls back to the Accept header (text/csv selects CSV)
+ * - Defaults to JSON when nothing is specified
+ * @throws
+ * - Unsupported format error for unknown `format` values
+ */
+func negotiateFormat(c *gin.Context) (string, error) {
+	if format := strings.ToLower(c.Query("format")); format != "" {
+		switch format {
+		case formatJSON, formatCSV:
+			return format, nil
+		default:
+			return "", internal.NewMessageError(internal.MsgFormatUnsupported, "format", format)
+		}
+	}
+	if c.NegotiateFormat(gin.MIMEJSON, mimeCSV) == mimeCSV {
+		return formatCSV, nil
+	}
+	return formatJSON, nil
+}
+
+/**
+ * renderCSV writes a slice of structs as a CSV document
+ * @param {*gin.Context} c - Gin context
+ * @param {int} status - HTTP status code
+ * @param {interface{}} data - Slice of structs (or pointers to structs) to serialize
+ * @param {[]string} fields - Columns to emit, nil for all
+ * @description
+ * - Uses the json tags of the struct fields as CSV column names
+ * - Formats time values as RFC3339
+ * - Returns an internal error response if the data cannot be serialized
+ */
+func renderCSV(c *gin.Context, status int, data interface{}, fields []string) {
+	header, rows, err := toCSVRecords(data, fields)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    "internal.error",
+			"message": internal.Localized(c, internal.MsgInternalError),
+		})
+		return
+	}
+
+	c.Status(status)
+	c.Header("Content-Type", mimeCSV+"; charset=utf-8")
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write(header)
+	_ = w.WriteAll(rows)
+}
+
+/**
+ * toCSVRecords converts a slice of structs into CSV header and rows
+ * @param {interface{}} data - Slice of structs (or pointers to structs)
+ * @param {[]string} fields - Columns to emit, nil for all
+ * @returns {[]string, [][]string, error} Header, rows and error if data is not a slice of structs
+ * @description
+ * - Only exported fields with a json tag other than "-" are emitted
+ * - Nil pointer elements produce empty cells
+ */
+func toCSVRecords(data interface{}, fields []string) ([]string, [][]string, error) {
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Slice {
+		return nil, nil, fmt.Errorf("csv data must be a slice, got %s", v.Kind())
+	}
+	elemType := v.Type().Elem()
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("csv data must be a slice of structs, got %s", elemType.Kind())
+	}
+
+	var header []string
+	var indexes []int
+	names, allIndexes := jsonFields(elemType)
+	for i, name := range names {
+		if fields != nil && !containsString(fields, name) {
+			continue
+		}
+		header = append(header, name)
+		indexes = append(indexes, allIndexes[i])
+	}
+
+	rows := make([][]string, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		elem := v.Index(i)
+		if elem.Kind() == reflect.Ptr {
+			if elem.IsNil() {
+				rows = append(rows, make([]string, len(indexes)))
+				continue
+			}
+			elem = elem.Elem()
+		}
+		row := make([]string, 0, len(indexes))
+		for _, idx := range indexes {
+			row = append(row, formatCSVValue(elem.Field(idx)))
+		}
+		rows = append(rows, row)
+	}
+	return header, rows, nil
+}
+
+/**
+ * formatCSVValue formats a single struct field value for CSV output
+ * @param {reflect.Value} v - Field value
+ * @returns {string} String representation of the value
+ */
+func formatCSVValue(v reflect.Value) string {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+/**
+ * jsonFields lists the JSON field names of a struct type
+ * @param {reflect.Type} t - Struct type
+ * @returns {[]string, []int} Field names and the matching struct field indexes
+ * @description
+ * - Only exported fields with a json tag other than "-" are listed
+ */
+func jsonFields(t reflect.Type) ([]string, []int) {
+	var names []string
+	var indexes []int
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if !field.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		names = append(names, name)
+		indexes = append(indexes, i)
+	}
+	return names, indexes
+}
+
+/**
+ * parseFields reads the sparse fieldset requested with the `fields` query parameter
+ * @param {*gin.Context} c - Gin context
+ * @param {interface{}} model - Model value whose JSON fields may be selected
+ * @returns {[]string, error} Selected field names, nil when all fields are requested, and error if a name is unknown
+ * @description
+ * - Field names are comma separated, e.g. fields=id,client_id
+ * - Names are validated against the json tags of the model
+ * @throws
+ * - Unknown field error
+ */
+func parseFields(c *gin.Context, model interface{}) ([]string, error) {
+	param := c.Query("fields")
+	if param == "" {
+		return nil, nil
+	}
+	t := reflect.TypeOf(model)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	known, _ := jsonFields(t)
+
+	var fields []string
+	for _, name := range strings.Split(param, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !containsString(known, name) {
+			return nil, internal.NewMessageError(internal.MsgFieldsUnknown, "field", name)
+		}
+		if !containsString(fields, name) {
+			fields = append(fields, name)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, internal.NewMessageError(internal.MsgFieldsEmpty)
+	}
+	return fields, nil
+}
+
+/**
+ * projectFields restricts a response payload to the selected JSON fields
+ * @param {interface{}} data - Struct, pointer to struct, or slice of them
+ * @param {[]string} fields - Fields to keep, nil keeps the payload unchanged
+ * @returns {interface{}, error} Projected payload and error if it cannot be serialized
+ * @description
+ * - Works on the JSON representation, so json tags and custom marshalers apply
+ * - Slices are projected element by element
+ */
+func projectFields(data interface{}, fields []string) (interface{}, error) {
+	if fields == nil {
+		return data, nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	project := func(obj map[string]json.RawMessage) map[string]json.RawMessage {
+		if obj == nil {
+			return nil
+		}
+		projected := make(map[string]json.RawMessage, len(fields))
+		for _, name := range fields {
+			if value, ok := obj[name]; ok {
+				projected[name] = value
+			}
+		}
+		return projected
+	}
+
+	if v := reflect.ValueOf(data); v.Kind() == reflect.Slice {
+		var objs []map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &objs); err != nil {
+			return nil, err
+		}
+		projected := make([]map[string]json.RawMessage, 0, len(objs))
+		for _, obj := range objs {
+			projected = append(projected, project(obj))
+		}
+		return projected, nil
+	}
+
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, err
+	}
+	return project(obj), nil
+}
+
+/**
+ * containsString reports whether a string slice contains a value
+ * @param {[]string} values - Slice to search
+ * @param {string} value - Value to look for
+ * @returns {bool} True if found
+ */
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
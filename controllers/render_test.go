@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zgsm-ai/client-manager/models"
+)
+
+func TestParseFields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name    string
+		query   string
+		want    []string
+		wantErr bool
+	}{
+		{"all fields", "", nil, false},
+		{"subset", "?fields=id,client_id", []string{"id", "client_id"}, false},
+		{"spaces and duplicates", "?fields=%20id%20,id,,file_name", []string{"id", "file_name"}, false},
+		{"unknown field", "?fields=id,password", nil, true},
+		{"untagged field", "?fields=ID", nil, true},
+		{"only separators", "?fields=,,", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/"+tt.query, nil)
+			got, err := parseFields(c, models.Log{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProjectFields(t *testing.T) {
+	log := models.Log{ID: 7, ClientID: "c1", FileName: "a.log", SizeBytes: 5}
+	tests := []struct {
+		name   string
+		data   interface{}
+		fields []string
+		want   string
+	}{
+		{"struct", log, []string{"id", "client_id"}, `{"client_id":"c1","id":7}`},
+		{"pointer", &log, []string{"file_name"}, `{"file_name":"a.log"}`},
+		{"slice", []models.Log{log, {ID: 8, ClientID: "c2"}}, []string{"id"}, `[{"id":7},{"id":8}]`},
+		{"empty slice", []models.Log{}, []string{"id"}, `[]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projected, err := projectFields(tt.data, tt.fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := json.Marshal(projected)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("projected = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if got, _ := projectFields(log, nil); !reflect.DeepEqual(got, log) {
+		t.Errorf("projection without fields changed the payload to %v", got)
+	}
+}
@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/zgsm-ai/client-manager/internal"
+	"github.com/zgsm-ai/client-manager/services"
+)
+
+/**
+ * SearchController handles HTTP requests for the admin search
+ * @description
+ * - Searches logs and the audit trail from one endpoint
+ * - Integrates with SearchService for business logic
+ */
+type SearchController struct {
+	searchService *services.SearchService
+	log           *logrus.Logger
+}
+
+/**
+ * NewSearchController creates a new SearchController instance
+ * @param {logrus.Logger} log - Logger instance
+ * @param {*services.SearchService} searchService - Search service
+ * @returns {*SearchController} New SearchController instance
+ */
+func NewSearchController(log *logrus.Logger, searchService *services.SearchService) *SearchController {
+	return &SearchController{
+		searchService: searchService,
+		log:           log,
+	}
+}
+
+// Search handles GET /search request
+// @Summary Search logs and audit entries
+// @Description Search several resources at once, hits are merged newest first and tagged with their source (admin only)
+// @Tags Search
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param q query string true "Search term, matched as a substring"
+// @Param types query string false "Comma separated sources to search: log, audit. All when empty"
+// @Param limit query int false "Maximum hits per source, defaults to pagination.search.default and is capped at pagination.search.max" default(20)
+// @Success 200 {object} map[string]interface{} "Merged hits with per-source counts"
+// @Failure 400 {object} map[string]interface{} "Invalid parameters"
+// @Failure 401 {object} map[string]interface{} "Missing or invalid admin key"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /client-manager/api/v1/search [get]
+func (sc *SearchController) Search(c *gin.Context) {
+	// Record start time for metrics
+	start := time.Now()
+
+	var args services.SearchArgs
+	if err := c.ShouldBindQuery(&args); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    "argument.invalid",
+			"message": internal.LocalizeError(c.GetHeader("Accept-Language"), err),
+		})
+		return
+	}
+
+	results, sources, err := sc.searchService.Search(c.Request.Context(), &args)
+	if err != nil {
+		respondError(c, sc.log, err)
+		return
+	}
+
+	// Record successful search metrics
+	duration := time.Since(start)
+	internal.RecordHTTPRequest("GET", "/client-manager/api/v1/search", http.StatusOK, duration)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    "success",
+		"message": "Search completed successfully",
+		"data":    results,
+		"sources": sources,
+	})
+}
@@ -0,0 +1,159 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/zgsm-ai/client-manager/models"
+)
+
+/**
+ * AuditDAO handles data access operations for audit entries
+ * @description
+ * - Appends audit entries, never updates or deletes them
+ * - Joins the transaction carried by the context when present
+ * - Supports filtering by resource and actor
+ * - Caches list totals for approximate counts, new entries invalidate them
+ */
+type AuditDAO struct {
+	db     *gorm.DB
+	log    *logrus.Logger
+	counts *countCache
+}
+
+/**
+ * NewAuditDAO creates a new AuditDAO instance
+ * @param {*gorm.DB} db - Database connection
+ * @param {logrus.Logger} log - Logger instance
+ * @returns {*AuditDAO} New AuditDAO instance
+ */
+func NewAuditDAO(db *gorm.DB, log *logrus.Logger) *AuditDAO {
+	return &AuditDAO{
+		db:     db,
+		log:    log,
+		counts: newCountCache(),
+	}
+}
+
+/**
+ * Create appends an audit entry
+ * @param {context.Context} ctx - Context, may carry the transaction of the audited operation
+ * @param {*models.AuditEntry} entry - Audit entry to store
+ * @returns {error} Error if any
+ * @throws
+ * - Database insert errors
+ */
+func (dao *AuditDAO) Create(ctx context.Context, entry *models.AuditEntry) error {
+	if dao.db == nil {
+		return fmt.Errorf("Database is not initialized")
+	}
+
+	err := withRetry(ctx, dao.log, "create_audit_entry", func() error {
+		return dbFromContext(ctx, dao.db).Create(entry).Error
+	})
+	if err != nil {
+		dao.log.WithError(err).Error("Failed to create audit entry")
+		return err
+	}
+	dao.counts.invalidate()
+	return nil
+}
+
+/**
+ * List retrieves audit entries with filtering and pagination
+ * @param {context.Context} ctx - Context for request cancellation
+ * @param {string} resource - Resource filter (optional)
+ * @param {string} actor - Actor filter (optional)
+ * @param {int} page - Page number
+ * @param {int} pageSize - Number of items per page
+ * @param {CountMode} count - How to compute the total of the matching entries
+ * @returns {[]models.AuditEntry, *int64, error} Audit entries, total count (nil if not counted), and error
+ * @description
+ * - Returns newest entries first
+ * - CountApproximate reuses a total cached for the same filters
+ * - With CountNone, skips the COUNT query and fetches pageSize+1 entries
+ * @throws
+ * - Database query errors
+ */
+func (dao *AuditDAO) List(ctx context.Context, resource, actor string, page, pageSize int, count CountMode) ([]models.AuditEntry, *int64, error) {
+	if dao.db == nil {
+		return nil, nil, fmt.Errorf("Database is not initialized")
+	}
+
+	query := dbFromContext(ctx, dao.db).Model(&models.AuditEntry{})
+	if resource != "" {
+		query = query.Where("resource = ?", resource)
+	}
+	if actor != "" {
+		query = query.Where("actor = ?", actor)
+	}
+
+	query = query.Session(&gorm.Session{})
+
+	var total *int64
+	limit := pageSize
+	key := countKey(resource, actor)
+	if count == CountApproximate {
+		if cached, ok := dao.counts.get(key); ok {
+			total = &cached
+		}
+	}
+	if total == nil && count != CountNone {
+		total = new(int64)
+		err := withRetry(ctx, dao.log, "count_audit_entries", func() error {
+			return query.Count(total).Error
+		})
+		if err != nil {
+			dao.log.WithError(err).Error("Failed to count audit entries")
+			return nil, nil, err
+		}
+		dao.counts.set(key, *total)
+	} else if total == nil {
+		limit++
+	}
+
+	var entries []models.AuditEntry
+	offset := (page - 1) * pageSize
+	err := withRetry(ctx, dao.log, "list_audit_entries", func() error {
+		entries = nil
+		return query.Order("id DESC").Offset(offset).Limit(limit).Find(&entries).Error
+	})
+	if err != nil {
+		dao.log.WithError(err).Error("Failed to list audit entries")
+		return nil, nil, err
+	}
+	return entries, total, nil
+}
+
+/**
+ * Search finds audit entries whose actor, resource or resource id contains a term
+ * @param {context.Context} ctx - Context for request cancellation
+ * @param {string} term - Search term, matched case-insensitively for ASCII
+ * @param {int} limit - Maximum number of entries to return
+ * @returns {[]models.AuditEntry, error} Matching entries, newest first, and error if any
+ * @throws
+ * - Database query errors
+ */
+func (dao *AuditDAO) Search(ctx context.Context, term string, limit int) ([]models.AuditEntry, error) {
+	if dao.db == nil {
+		return nil, fmt.Errorf("Database is not initialized")
+	}
+
+	pattern := containsPattern(term)
+	var entries []models.AuditEntry
+	err := withRetry(ctx, dao.log, "search_audit_entries", func() error {
+		entries = nil
+		return dbFromContext(ctx, dao.db).
+			Where(`actor LIKE ? ESCAPE '\' OR resource LIKE ? ESCAPE '\' OR resource_id LIKE ? ESCAPE '\'`,
+				pattern, pattern, pattern).
+			Order("id DESC").Limit(limit).Find(&entries).Error
+	})
+	if err != nil {
+		dao.log.WithError(err).Error("Failed to search audit entries")
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,104 @@
+package dao
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zgsm-ai/client-manager/internal"
+)
+
+// CountMode selects how list queries compute their total
+type CountMode int
+
+// Count modes
+const (
+	CountNone        CountMode = iota // skip the COUNT query
+	CountExact                        // run the COUNT query
+	CountApproximate                  // serve a recently cached COUNT when available
+)
+
+// countCacheMaxEntries bounds the number of distinct filter combinations cached
+const countCacheMaxEntries = 1024
+
+// cachedCount is a cached total and its expiry
+type cachedCount struct {
+	total   int64
+	expires time.Time
+}
+
+/**
+ * countCache caches list totals per filter combination in process memory
+ * @description
+ * - Entries live for database.count_cache_ttl, 0 disables caching
+ * - Writes to the table drop all entries since any filter may be affected
+ * - A write inside an uncommitted transaction may be missed by a count cached
+ *   concurrently, such totals are corrected when the entry expires
+ */
+type countCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedCount
+}
+
+/**
+ * newCountCache creates an empty countCache
+ * @returns {*countCache} New countCache instance
+ */
+func newCountCache() *countCache {
+	return &countCache{entries: map[string]cachedCount{}}
+}
+
+/**
+ * get returns a cached total that has not expired
+ * @param {string} key - Filter key built by countKey
+ * @returns {int64, bool} Cached total and whether it was found
+ */
+func (c *countCache) get(key string) (int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || internal.Now().After(entry.expires) {
+		return 0, false
+	}
+	return entry.total, true
+}
+
+/**
+ * set caches a total for database.count_cache_ttl
+ * @param {string} key - Filter key built by countKey
+ * @param {int64} total - Total to cache
+ * @description
+ * - Starts over once countCacheMaxEntries filter combinations are cached
+ */
+func (c *countCache) set(key string, total int64) {
+	ttl := internal.GetCountCacheTTL()
+	if ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= countCacheMaxEntries {
+		c.entries = map[string]cachedCount{}
+	}
+	c.entries[key] = cachedCount{total: total, expires: internal.Now().Add(ttl)}
+}
+
+/**
+ * invalidate drops all cached totals
+ */
+func (c *countCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) > 0 {
+		c.entries = map[string]cachedCount{}
+	}
+}
+
+/**
+ * countKey builds the cache key of a filter combination
+ * @param {...string} filters - Filter values in a fixed order, empty for unset
+ * @returns {string} Cache key
+ */
+func countKey(filters ...string) string {
+	return strings.Join(filters, "\x00")
+}
@@ -0,0 +1,92 @@
+package dao
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/zgsm-ai/client-manager/internal/testutil"
+	"github.com/zgsm-ai/client-manager/models"
+)
+
+func TestListLogsApproximateTotal(t *testing.T) {
+	ctx := context.Background()
+	// bypass adds a log without going through the DAO, so the cache is not invalidated
+	bypass := func(t *testing.T, dao *LogDAO, db *gorm.DB) {
+		if err := db.Create(&models.Log{ClientID: "c1", FileName: "raw.log"}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	upsert := func(t *testing.T, dao *LogDAO, db *gorm.DB) {
+		if _, err := dao.Upsert(ctx, &models.Log{ClientID: "c1", FileName: "new.log"}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		ttl       time.Duration
+		write     func(t *testing.T, dao *LogDAO, db *gorm.DB)
+		mode      CountMode
+		wantTotal int64
+	}{
+		{"cached total is served", time.Minute, bypass, CountApproximate, 1},
+		{"exact count ignores the cache", time.Minute, bypass, CountExact, 2},
+		{"insert invalidates the cache", time.Minute, upsert, CountApproximate, 2},
+		{"caching disabled", 0, bypass, CountApproximate, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.SetConfig(t, "database.count_cache_ttl", tt.ttl)
+			dao, db := newTestLogDAO(t, false)
+			if err := db.Create(&models.Log{ClientID: "c1", FileName: "a.log"}).Error; err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := dao.ListLogs(ctx, "c1", "", "", 1, 10, CountApproximate); err != nil {
+				t.Fatal(err)
+			}
+
+			tt.write(t, dao, db)
+			_, total, err := dao.ListLogs(ctx, "c1", "", "", 1, 10, tt.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if total == nil || *total != tt.wantTotal {
+				t.Errorf("total = %v, want %d", total, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestCountCache(t *testing.T) {
+	testutil.SetConfig(t, "database.count_cache_ttl", time.Minute)
+	c := newCountCache()
+	c.set(countKey("c1", ""), 3)
+	c.set(countKey("", "c1"), 5)
+
+	tests := []struct {
+		key   string
+		total int64
+		found bool
+	}{
+		{countKey("c1", ""), 3, true},
+		{countKey("", "c1"), 5, true},
+		{countKey("c2", ""), 0, false},
+	}
+	for _, tt := range tests {
+		if total, found := c.get(tt.key); total != tt.total || found != tt.found {
+			t.Errorf("get(%q) = %d, %v, want %d, %v", tt.key, total, found, tt.total, tt.found)
+		}
+	}
+
+	c.entries[countKey("old")] = cachedCount{total: 1, expires: time.Now().Add(-time.Second)}
+	if _, found := c.get(countKey("old")); found {
+		t.Errorf("expired total was served")
+	}
+	c.invalidate()
+	if _, found := c.get(countKey("c1", "")); found {
+		t.Errorf("total was served after invalidate")
+	}
+}
@@ -2,25 +2,32 @@ package dao
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/zgsm-ai/client-manager/models"
 )
 
+// ErrLastLineNoMismatch is returned by Upsert when the stored LastLineNo differs from the expected one
+var ErrLastLineNoMismatch = errors.New("last line number does not match")
+
 /**
  * LogDAO handles data access operations for log data
  * @description
  * - Provides CRUD operations for log data using GORM
  * - Supports client and user based log filtering
+ * - Caches list totals for approximate counts, writes invalidate them
  * - Implements database operations for performance optimization
  */
 type LogDAO struct {
-	db  *gorm.DB
-	log *logrus.Logger
+	db     *gorm.DB
+	log    *logrus.Logger
+	counts *countCache
 }
 
 /**
@@ -31,67 +38,253 @@ type LogDAO struct {
  */
 func NewLogDAO(db *gorm.DB, log *logrus.Logger) *LogDAO {
 	return &LogDAO{
-		db:  db,
-		log: log,
+		db:     db,
+		log:    log,
+		counts: newCountCache(),
+	}
+}
+
+/**
+ * Transaction runs fn inside a database transaction
+ * @param {context.Context} ctx - Context for request cancellation
+ * @param {func(context.Context) error} fn - Function performing the writes
+ * @returns {error} Error returned by fn or by the commit
+ * @description
+ * - DAO calls made with the context passed to fn join the transaction
+ * - Rolls back when fn returns an error
+ */
+func (dao *LogDAO) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if dao.db == nil {
+		return fmt.Errorf("Database is not initialized")
+	}
+	return runInTx(ctx, dao.db, dao.log, fn)
+}
+
+/**
+ * GetLog retrieves the log record of a client file
+ * @param {context.Context} ctx - Context for request cancellation
+ * @param {string} clientID - Client identifier
+ * @param {string} fileName - File name
+ * @returns {*models.Log, error} Log record, nil if not found, and error if any
+ * @throws
+ * - Database query errors
+ */
+func (dao *LogDAO) GetLog(ctx context.Context, clientID, fileName string) (*models.Log, error) {
+	if dao.db == nil {
+		return nil, fmt.Errorf("Database is not initialized")
+	}
+
+	var log models.Log
+	err := withRetry(ctx, dao.log, "get_log", func() error {
+		return dbFromContext(ctx, dao.db).Where("client_id = ? AND file_name = ?", clientID, fileName).First(&log).Error
+	})
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		dao.log.WithError(err).Error("Failed to get log")
+		return nil, err
 	}
+	return &log, nil
 }
 
 /**
  * Upsert creates or updates a log record
  * @param {context.Context} ctx - Context for request cancellation
  * @param {*models.Log} log - Log data to upsert
- * @returns {error} Error if any
+ * @param {*int64} ifLastLineNo - Only update a record with this LastLineNo, nil for unconditional
+ * @returns {bool, error} True if a new record was created, and error if any
  * @description
  * - Creates new log record if not exists
  * - Updates existing record if found
  * - Uses ClientID and FileName as unique identifier
+ * - Runs INSERT ... ON CONFLICT DO NOTHING and, when the row exists, an UPDATE
+ *   in one transaction, concurrent uploads of the same file cannot create
+ *   duplicate rows and the insert's row count tells whether the record is new
+ * - On update the line range becomes the union of the stored and uploaded ranges,
+ *   written with CASE so the statement does not depend on SQLite's scalar MIN/MAX
+ * - With ifLastLineNo the update is conditioned on the stored LastLineNo, a
+ *   concurrent append in between makes it fail with ErrLastLineNoMismatch
+ * - Timestamps are set by gorm in UTC, CreatedAt is kept on update
+ * - log is reloaded with the stored record, including the merged line range
  * - Logs upsert operation
  * @throws
+ * - ErrLastLineNoMismatch when ifLastLineNo does not match the stored record
  * - Database operation errors
  */
-func (dao *LogDAO) Upsert(ctx context.Context, log *models.Log) error {
+func (dao *LogDAO) Upsert(ctx context.Context, log *models.Log, ifLastLineNo *int64) (bool, error) {
 	if dao.db == nil {
-		return fmt.Errorf("Database is not initialized")
+		return false, fmt.Errorf("Database is not initialized")
 	}
 
-	// Set timestamps
-	now := time.Now()
-	if log.CreatedAt.IsZero() {
-		log.CreatedAt = now
+	insertIfMissing := clause.OnConflict{
+		Columns:   []clause.Column{{Name: "client_id"}, {Name: "file_name"}},
+		DoNothing: true,
 	}
-	log.UpdatedAt = now
 
-	// Check if log record exists
-	var existingLog models.Log
-	err := dao.db.Where("client_id = ? AND file_name = ?", log.ClientID, log.FileName).First(&existingLog).Error
+	var stored models.Log
+	var created bool
+	err := runInTx(ctx, dao.db, dao.log, func(ctx context.Context) error {
+		db := dbFromContext(ctx, dao.db)
 
-	if err == gorm.ErrRecordNotFound {
-		// Create new record
-		err = dao.db.Create(log).Error
-		if err != nil {
-			dao.log.WithError(err).Error("Failed to create log")
-			return err
+		log.ID = 0
+		result := db.Clauses(insertIfMissing).Create(log)
+		if result.Error != nil {
+			return result.Error
 		}
-	} else if err != nil {
-		// Database error
-		dao.log.WithError(err).Error("Failed to check existing log")
-		return err
-	} else {
-		// Update existing record
-		log.ID = existingLog.ID
-		err = dao.db.Save(log).Error
-		if err != nil {
-			dao.log.WithError(err).Error("Failed to update log")
-			return err
+		created = result.RowsAffected > 0
+
+		if !created {
+			query := db.Model(&models.Log{}).Where("client_id = ? AND file_name = ?", log.ClientID, log.FileName)
+			if ifLastLineNo != nil {
+				query = query.Where("last_line_no = ?", *ifLastLineNo)
+			}
+			result = query.Updates(map[string]interface{}{
+				"user_id":       log.UserID,
+				"module_name":   log.ModuleName,
+				"size_bytes":    log.SizeBytes,
+				"content_hash":  log.ContentHash,
+				"line_count":    log.LineCount,
+				"compressed":    log.Compressed,
+				"updated_at":    log.UpdatedAt,
+				"first_line_no": gorm.Expr("CASE WHEN first_line_no < ? THEN first_line_no ELSE ? END", log.FirstLineNo, log.FirstLineNo),
+				"last_line_no":  gorm.Expr("CASE WHEN last_line_no > ? THEN last_line_no ELSE ? END", log.LastLineNo, log.LastLineNo),
+			})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrLastLineNoMismatch
+			}
 		}
+
+		stored = models.Log{}
+		return db.Where("client_id = ? AND file_name = ?", log.ClientID, log.FileName).First(&stored).Error
+	})
+	if errors.Is(err, ErrLastLineNoMismatch) {
+		return false, err
+	}
+	if err != nil {
+		dao.log.WithError(err).Error("Failed to upsert log")
+		return false, err
+	}
+
+	*log = stored
+	if created {
+		dao.counts.invalidate()
 	}
 
 	dao.log.WithFields(logrus.Fields{
 		"client_id": log.ClientID,
 		"file_name": log.FileName,
 		"user_id":   log.UserID,
+		"created":   created,
 	}).Debug("Successfully upserted log")
 
+	return created, nil
+}
+
+/**
+ * CountByUser counts the log records of a user
+ * @param {context.Context} ctx - Context for request cancellation
+ * @param {string} userID - User identifier
+ * @returns {int64, error} Number of records and error if any
+ * @throws
+ * - Database query errors
+ */
+func (dao *LogDAO) CountByUser(ctx context.Context, userID string) (int64, error) {
+	if dao.db == nil {
+		return 0, fmt.Errorf("Database is not initialized")
+	}
+
+	var count int64
+	err := withRetry(ctx, dao.log, "count_user_logs", func() error {
+		return dbFromContext(ctx, dao.db).Model(&models.Log{}).Where("user_id = ?", userID).Count(&count).Error
+	})
+	if err != nil {
+		dao.log.WithError(err).WithField("user_id", userID).Error("Failed to count user logs")
+		return 0, err
+	}
+	return count, nil
+}
+
+/**
+ * FindByContentHash retrieves the most recently updated log of a user with the given content
+ * @param {context.Context} ctx - Context for request cancellation
+ * @param {string} userID - User identifier
+ * @param {string} contentHash - Hex encoded SHA-256 of the file content
+ * @returns {*models.Log, error} Log record, nil if not found, and error if any
+ * @throws
+ * - Database query errors
+ */
+func (dao *LogDAO) FindByContentHash(ctx context.Context, userID, contentHash string) (*models.Log, error) {
+	if dao.db == nil {
+		return nil, fmt.Errorf("Database is not initialized")
+	}
+
+	var log models.Log
+	err := withRetry(ctx, dao.log, "find_log_by_hash", func() error {
+		return dbFromContext(ctx, dao.db).Where("user_id = ? AND content_hash = ?", userID, contentHash).
+			Order("updated_at DESC").First(&log).Error
+	})
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		dao.log.WithError(err).WithField("user_id", userID).Error("Failed to find log by content hash")
+		return nil, err
+	}
+	return &log, nil
+}
+
+/**
+ * LeastRecentByUser retrieves the least recently updated log records of a user
+ * @param {context.Context} ctx - Context for request cancellation
+ * @param {string} userID - User identifier
+ * @param {int} limit - Maximum number of records
+ * @returns {[]models.Log, error} Records, oldest update first, and error if any
+ * @throws
+ * - Database query errors
+ */
+func (dao *LogDAO) LeastRecentByUser(ctx context.Context, userID string, limit int) ([]models.Log, error) {
+	if dao.db == nil {
+		return nil, fmt.Errorf("Database is not initialized")
+	}
+
+	var logs []models.Log
+	err := withRetry(ctx, dao.log, "least_recent_user_logs", func() error {
+		logs = nil
+		return dbFromContext(ctx, dao.db).Where("user_id = ?", userID).
+			Order("updated_at ASC, id ASC").Limit(limit).Find(&logs).Error
+	})
+	if err != nil {
+		dao.log.WithError(err).WithField("user_id", userID).Error("Failed to get least recent user logs")
+		return nil, err
+	}
+	return logs, nil
+}
+
+/**
+ * DeleteLog deletes a log record by ID
+ * @param {context.Context} ctx - Context for request cancellation
+ * @param {uint} id - Log record ID
+ * @returns {error} Error if any
+ * @throws
+ * - Database delete errors
+ */
+func (dao *LogDAO) DeleteLog(ctx context.Context, id uint) error {
+	if dao.db == nil {
+		return fmt.Errorf("Database is not initialized")
+	}
+
+	err := withRetry(ctx, dao.log, "delete_log", func() error {
+		return dbFromContext(ctx, dao.db).Delete(&models.Log{}, id).Error
+	})
+	if err != nil {
+		dao.log.WithError(err).WithField("id", id).Error("Failed to delete log")
+		return err
+	}
+	dao.counts.invalidate()
 	return nil
 }
 
@@ -103,22 +296,26 @@ func (dao *LogDAO) Upsert(ctx context.Context, log *models.Log) error {
  * @param {string} fileName - File name filter (optional)
  * @param {int} page - Page number
  * @param {int} pageSize - Number of items per page
- * @returns {[]models.Log, int64, error} List of logs, total count, and error
+ * @param {CountMode} count - How to compute the total of the matching records
+ * @returns {[]models.Log, *int64, error} List of logs, total count (nil if not counted), and error
  * @description
  * - Retrieves log records with optional filtering
  * - Supports pagination for large datasets
  * - Returns total count for frontend pagination
+ * - CountApproximate reuses a total cached for the same filters
+ * - With CountNone, skips the COUNT query and fetches pageSize+1 rows
+ *   so the caller can tell whether a next page exists
  * - Combines multiple filters with AND logic
  * @throws
  * - Database query errors
  */
-func (dao *LogDAO) ListLogs(ctx context.Context, clientID, userID, fileName string, page, pageSize int) ([]models.Log, int64, error) {
+func (dao *LogDAO) ListLogs(ctx context.Context, clientID, userID, fileName string, page, pageSize int, count CountMode) ([]models.Log, *int64, error) {
 	if dao.db == nil {
-		return nil, 0, fmt.Errorf("Database is not initialized")
+		return nil, nil, fmt.Errorf("Database is not initialized")
 	}
 
 	// Build database query
-	query := dao.db.Model(&models.Log{})
+	query := dbFromContext(ctx, dao.db).Model(&models.Log{})
 
 	if clientID != "" {
 		query = query.Where("client_id = ?", clientID)
@@ -130,12 +327,30 @@ func (dao *LogDAO) ListLogs(ctx context.Context, clientID, userID, fileName stri
 		query = query.Where("file_name = ?", fileName)
 	}
 
-	// Get total count
-	var total int64
-	err := query.Count(&total).Error
-	if err != nil {
-		dao.log.WithError(err).Error("Failed to count logs")
-		return nil, 0, err
+	// Start a new session so retried statements do not accumulate clauses
+	query = query.Session(&gorm.Session{})
+
+	// Get total count, or fetch one extra row to detect a next page
+	var total *int64
+	limit := pageSize
+	key := countKey(clientID, userID, fileName)
+	if count == CountApproximate {
+		if cached, ok := dao.counts.get(key); ok {
+			total = &cached
+		}
+	}
+	if total == nil && count != CountNone {
+		total = new(int64)
+		err := withRetry(ctx, dao.log, "count_logs", func() error {
+			return query.Count(total).Error
+		})
+		if err != nil {
+			dao.log.WithError(err).Error("Failed to count logs")
+			return nil, nil, err
+		}
+		dao.counts.set(key, *total)
+	} else if total == nil {
+		limit++
 	}
 
 	// Calculate pagination
@@ -143,52 +358,237 @@ func (dao *LogDAO) ListLogs(ctx context.Context, clientID, userID, fileName stri
 
 	// Execute query with pagination and ordering
 	var logs []models.Log
-	err = query.Order("updated_at DESC").Offset(offset).Limit(pageSize).Find(&logs).Error
+	err := withRetry(ctx, dao.log, "list_logs", func() error {
+		logs = nil
+		return query.Order("updated_at DESC").Offset(offset).Limit(limit).Find(&logs).Error
+	})
 	if err != nil {
 		dao.log.WithError(err).Error("Failed to list logs")
-		return nil, 0, err
+		return nil, nil, err
 	}
 
 	return logs, total, nil
 }
 
 /**
- * DeleteOldLogs deletes logs older than specified date
+ * Search finds log records whose client, user, file or module name contains a term
+ * @param {context.Context} ctx - Context for request cancellation
+ * @param {string} term - Search term, matched case-insensitively for ASCII
+ * @param {int} limit - Maximum number of records to return
+ * @returns {[]models.Log, error} Matching records, most recently updated first, and error if any
+ * @throws
+ * - Database query errors
+ */
+func (dao *LogDAO) Search(ctx context.Context, term string, limit int) ([]models.Log, error) {
+	if dao.db == nil {
+		return nil, fmt.Errorf("Database is not initialized")
+	}
+
+	pattern := containsPattern(term)
+	var logs []models.Log
+	err := withRetry(ctx, dao.log, "search_logs", func() error {
+		logs = nil
+		return dbFromContext(ctx, dao.db).
+			Where(`client_id LIKE ? ESCAPE '\' OR user_id LIKE ? ESCAPE '\' OR file_name LIKE ? ESCAPE '\' OR module_name LIKE ? ESCAPE '\'`,
+				pattern, pattern, pattern, pattern).
+			Order("updated_at DESC").Limit(limit).Find(&logs).Error
+	})
+	if err != nil {
+		dao.log.WithError(err).Error("Failed to search logs")
+		return nil, err
+	}
+	return logs, nil
+}
+
+/**
+ * SummarizeClient aggregates the logs of a client
+ * @param {context.Context} ctx - Context for request cancellation
+ * @param {string} clientID - Client identifier
+ * @returns {*models.ClientLogSummary, error} Summary, zero totals if the client has no logs, and error if any
+ * @description
+ * - Counts files and sums the stored SizeBytes in one aggregate query
+ * - Date range spans the first creation to the last update, read with
+ *   ordered queries because SQLite returns MIN/MAX of timestamps as text
+ * @throws
+ * - Database query errors
+ */
+func (dao *LogDAO) SummarizeClient(ctx context.Context, clientID string) (*models.ClientLogSummary, error) {
+	if dao.db == nil {
+		return nil, fmt.Errorf("Database is not initialized")
+	}
+
+	summary := &models.ClientLogSummary{ClientID: clientID}
+	err := withRetry(ctx, dao.log, "summarize_client", func() error {
+		return dbFromContext(ctx, dao.db).Model(&models.Log{}).
+			Select("COUNT(*) AS total_files, COALESCE(SUM(size_bytes), 0) AS total_bytes").
+			Where("client_id = ?", clientID).Scan(summary).Error
+	})
+	if err != nil {
+		dao.log.WithError(err).WithField("client_id", clientID).Error("Failed to summarize client logs")
+		return nil, err
+	}
+	if summary.TotalFiles == 0 {
+		return summary, nil
+	}
+
+	var first, last models.Log
+	err = withRetry(ctx, dao.log, "summarize_client_range", func() error {
+		db := dbFromContext(ctx, dao.db).Where("client_id = ?", clientID)
+		if err := db.Session(&gorm.Session{}).Order("created_at ASC").Take(&first).Error; err != nil {
+			return err
+		}
+		return db.Session(&gorm.Session{}).Order("updated_at DESC").Take(&last).Error
+	})
+	if err != nil {
+		dao.log.WithError(err).WithField("client_id", clientID).Error("Failed to get client log date range")
+		return nil, err
+	}
+	summary.FirstUploadAt = &first.CreatedAt
+	summary.LastUploadAt = &last.UpdatedAt
+	return summary, nil
+}
+
+/**
+ * CountByDay counts logs per day of their last update
+ * @param {context.Context} ctx - Context for request cancellation
+ * @param {time.Time} from - Start of the range, inclusive
+ * @param {time.Time} to - End of the range, exclusive
+ * @returns {[]models.DailyLogCount, error} Counts ordered by date, days without logs are omitted, and error if any
+ * @throws
+ * - Database query errors
+ */
+func (dao *LogDAO) CountByDay(ctx context.Context, from, to time.Time) ([]models.DailyLogCount, error) {
+	if dao.db == nil {
+		return nil, fmt.Errorf("Database is not initialized")
+	}
+
+	var counts []models.DailyLogCount
+	err := withRetry(ctx, dao.log, "count_logs_by_day", func() error {
+		counts = nil
+		return dbFromContext(ctx, dao.db).Model(&models.Log{}).
+			Select("DATE(updated_at) AS date, COUNT(*) AS count").
+			Where("updated_at >= ? AND updated_at < ?", from, to).
+			Group("DATE(updated_at)").Order("date").Scan(&counts).Error
+	})
+	if err != nil {
+		dao.log.WithError(err).Error("Failed to count logs by day")
+		return nil, err
+	}
+	return counts, nil
+}
+
+/**
+ * CountByClient counts logs and stored bytes per client
  * @param {context.Context} ctx - Context for request cancellation
+ * @param {time.Time} from - Start of the range, inclusive
+ * @param {time.Time} to - End of the range, exclusive
+ * @returns {[]models.ClientLogCount, error} Counts ordered by number of files, descending, and error if any
+ * @description
+ * - Only logs updated within the range are counted
+ * @throws
+ * - Database query errors
+ */
+func (dao *LogDAO) CountByClient(ctx context.Context, from, to time.Time) ([]models.ClientLogCount, error) {
+	if dao.db == nil {
+		return nil, fmt.Errorf("Database is not initialized")
+	}
+
+	var counts []models.ClientLogCount
+	err := withRetry(ctx, dao.log, "count_logs_by_client", func() error {
+		counts = nil
+		return dbFromContext(ctx, dao.db).Model(&models.Log{}).
+			Select("client_id, COUNT(*) AS files, COALESCE(SUM(size_bytes), 0) AS bytes").
+			Where("updated_at >= ? AND updated_at < ?", from, to).
+			Group("client_id").Order("files DESC, client_id").Scan(&counts).Error
+	})
+	if err != nil {
+		dao.log.WithError(err).Error("Failed to count logs by client")
+		return nil, err
+	}
+	return counts, nil
+}
+
+/**
+ * ListModules counts logs per module name
+ * @param {context.Context} ctx - Context for request cancellation
+ * @param {string} clientID - Client identifier filter (optional)
+ * @returns {[]models.ModuleLogCount, error} Distinct module names ordered by name, and error if any
+ * @description
+ * - Logs uploaded without a module name are left out
+ * @throws
+ * - Database query errors
+ */
+func (dao *LogDAO) ListModules(ctx context.Context, clientID string) ([]models.ModuleLogCount, error) {
+	if dao.db == nil {
+		return nil, fmt.Errorf("Database is not initialized")
+	}
+
+	query := dbFromContext(ctx, dao.db).Model(&models.Log{}).Where("module_name <> ''")
+	if clientID != "" {
+		query = query.Where("client_id = ?", clientID)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var modules []models.ModuleLogCount
+	err := withRetry(ctx, dao.log, "list_log_modules", func() error {
+		modules = nil
+		return query.Select("module_name, COUNT(*) AS count").
+			Group("module_name").Order("module_name").Scan(&modules).Error
+	})
+	if err != nil {
+		dao.log.WithError(err).Error("Failed to list log modules")
+		return nil, err
+	}
+	return modules, nil
+}
+
+/**
+ * DeleteOldLogs deletes logs older than specified date
+ * @param {context.Context} ctx - Context carrying the purge transaction
  * @param {string} beforeDate - Delete logs before this date
- * @returns {int64, error} Number of deleted records and error if any
+ * @returns {[]models.Log, error} Deleted records and error if any
  * @description
  * - Performs cleanup of old log records
- * - Uses database delete operation for bulk deletion
- * - Returns count of deleted records
+ * - Reads the records before deleting them so callers can remove their files,
+ *   run it inside a transaction to keep both steps consistent
  * - Logs deletion operation
  * @throws
  * - Database delete errors
  */
-func (dao *LogDAO) DeleteOldLogs(ctx context.Context, beforeDate string) (int64, error) {
+func (dao *LogDAO) DeleteOldLogs(ctx context.Context, beforeDate string) ([]models.Log, error) {
 	if dao.db == nil {
-		return 0, fmt.Errorf("Database is not initialized")
+		return nil, fmt.Errorf("Database is not initialized")
 	}
 
 	// Parse the before date
 	parsedDate, err := time.Parse("2006-01-02", beforeDate)
 	if err != nil {
-		return 0, fmt.Errorf("invalid date format: %w", err)
+		return nil, fmt.Errorf("invalid date format: %w", err)
 	}
 
-	// Execute delete operation and get count
-	result := dao.db.Where("updated_at < ?", parsedDate).Delete(&models.Log{})
-	if result.Error != nil {
-		dao.log.WithError(result.Error).Error("Failed to delete old logs")
-		return 0, result.Error
+	// Collect and delete the old records
+	var deleted []models.Log
+	err = withRetry(ctx, dao.log, "delete_old_logs", func() error {
+		deleted = nil
+		db := dbFromContext(ctx, dao.db)
+		if err := db.Where("updated_at < ?", parsedDate).Find(&deleted).Error; err != nil {
+			return err
+		}
+		return db.Where("updated_at < ?", parsedDate).Delete(&models.Log{}).Error
+	})
+	if err != nil {
+		dao.log.WithError(err).Error("Failed to delete old logs")
+		return nil, err
+	}
+	deletedCount := len(deleted)
+	if deletedCount > 0 {
+		dao.counts.invalidate()
 	}
-
-	deletedCount := result.RowsAffected
 
 	dao.log.WithFields(logrus.Fields{
 		"before_date":   beforeDate,
 		"deleted_count": deletedCount,
 	}).Info("Successfully deleted old logs")
 
-	return deletedCount, nil
+	return deleted, nil
 }
@@ -0,0 +1,281 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/zgsm-ai/client-manager/internal"
+	"github.com/zgsm-ai/client-manager/internal/testutil"
+	"github.com/zgsm-ai/client-manager/models"
+)
+
+// newTestLogDAO opens a migrated temporary database, optionally caching prepared statements
+func newTestLogDAO(tb testing.TB, prepareStmt bool) (*LogDAO, *gorm.DB) {
+	tb.Helper()
+	db := testutil.NewDB(tb, &gorm.Config{PrepareStmt: prepareStmt, NowFunc: internal.Now}, &models.Log{}, &models.AuditEntry{})
+	return NewLogDAO(db, testutil.NewLogger()), db
+}
+
+// seedLogs creates n logs spread over three clients
+func seedLogs(tb testing.TB, db *gorm.DB, n int) {
+	tb.Helper()
+	logs := make([]models.Log, 0, n)
+	for i := 0; i < n; i++ {
+		logs = append(logs, models.Log{
+			ClientID:   fmt.Sprintf("client-%d", i%3),
+			UserID:     fmt.Sprintf("user_%d", i%2),
+			FileName:   fmt.Sprintf("app-%d.log", i),
+			ModuleName: "core",
+		})
+	}
+	if err := db.Create(&logs).Error; err != nil {
+		tb.Fatal(err)
+	}
+}
+
+func TestLogDAOWithPrepareStmt(t *testing.T) {
+	for _, prepareStmt := range []bool{false, true} {
+		t.Run(fmt.Sprintf("prepare_stmt=%v", prepareStmt), func(t *testing.T) {
+			dao, db := newTestLogDAO(t, prepareStmt)
+			seedLogs(t, db, 12)
+			ctx := context.Background()
+
+			// The same statements run repeatedly with different arguments
+			searches := []struct {
+				term string
+				want int
+			}{
+				{"client-1", 4},
+				{"app-1", 3},
+				{"user_1", 6},
+				{"user%", 0},
+				{"client-1", 4},
+			}
+			for _, s := range searches {
+				logs, err := dao.Search(ctx, s.term, 100)
+				if err != nil {
+					t.Fatalf("Search(%q): %v", s.term, err)
+				}
+				if len(logs) != s.want {
+					t.Errorf("Search(%q) found %d logs, want %d", s.term, len(logs), s.want)
+				}
+			}
+
+			lists := []struct {
+				clientID, userID string
+				want             int64
+			}{
+				{"client-0", "", 4},
+				{"client-0", "user_0", 2},
+				{"", "user_1", 6},
+				{"client-0", "", 4},
+			}
+			for _, l := range lists {
+				logs, total, err := dao.ListLogs(ctx, l.clientID, l.userID, "", 1, 100, CountExact)
+				if err != nil {
+					t.Fatalf("ListLogs(%q, %q): %v", l.clientID, l.userID, err)
+				}
+				if total == nil || *total != l.want || int64(len(logs)) != l.want {
+					t.Errorf("ListLogs(%q, %q) = %d logs, total %v, want %d", l.clientID, l.userID, len(logs), total, l.want)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkLogDAOQueries compares repeated queries with and without database.prepare_stmt
+func BenchmarkLogDAOQueries(b *testing.B) {
+	for _, prepareStmt := range []bool{false, true} {
+		b.Run(fmt.Sprintf("prepare_stmt=%v", prepareStmt), func(b *testing.B) {
+			dao, db := newTestLogDAO(b, prepareStmt)
+			seedLogs(b, db, 300)
+			ctx := context.Background()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := dao.ListLogs(ctx, fmt.Sprintf("client-%d", i%3), "", "", 1, 20, CountExact); err != nil {
+					b.Fatal(err)
+				}
+				if _, err := dao.Search(ctx, fmt.Sprintf("app-%d", i%300), 20); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestLogDAOUpsertReportsCreated(t *testing.T) {
+	dao, _ := newTestLogDAO(t, false)
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		fileName   string
+		lastLineNo int64
+		created    bool
+	}{
+		{"new file", "a.log", 10, true},
+		{"re-upload", "a.log", 20, false},
+		{"another file", "b.log", 5, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := &models.Log{ClientID: "c1", UserID: "u1", FileName: tt.fileName, LastLineNo: tt.lastLineNo}
+			created, err := dao.Upsert(ctx, log, nil)
+			if err != nil {
+				t.Fatalf("Upsert: %v", err)
+			}
+			if created != tt.created {
+				t.Errorf("created = %v, want %v", created, tt.created)
+			}
+			if log.LastLineNo != tt.lastLineNo {
+				t.Errorf("stored last_line_no = %d, want %d", log.LastLineNo, tt.lastLineNo)
+			}
+		})
+	}
+
+	logs, total, err := dao.ListLogs(ctx, "c1", "", "", 1, 10, CountExact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *total != 2 || len(logs) != 2 {
+		t.Errorf("stored %d logs, want 2", *total)
+	}
+}
+
+func TestLogDAOUpsertConcurrentRanges(t *testing.T) {
+	dao, db := newTestLogDAO(t, false)
+	ctx := context.Background()
+
+	// Every upload overlaps its neighbours, the stored range must cover them all
+	const uploads = 8
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var created int
+	errs := make(chan error, uploads)
+	for i := 0; i < uploads; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			log := &models.Log{ClientID: "c1", FileName: "a.log", FirstLineNo: int64(i*10 + 1), LastLineNo: int64(i*10 + 20)}
+			ok, err := dao.Upsert(ctx, log, nil)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if ok {
+				mu.Lock()
+				created++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("Upsert: %v", err)
+	}
+
+	var stored []models.Log
+	if err := db.Find(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 {
+		t.Fatalf("stored %d rows, want 1", len(stored))
+	}
+	if got, want := stored[0].FirstLineNo, int64(1); got != want {
+		t.Errorf("first_line_no = %d, want %d", got, want)
+	}
+	if got, want := stored[0].LastLineNo, int64((uploads-1)*10+20); got != want {
+		t.Errorf("last_line_no = %d, want %d", got, want)
+	}
+	if created != 1 {
+		t.Errorf("%d uploads reported created, want 1", created)
+	}
+}
+
+func TestLogDAOUpsertTimestamps(t *testing.T) {
+	dao, db := newTestLogDAO(t, false)
+	ctx := context.Background()
+
+	first := &models.Log{ClientID: "c1", FileName: "a.log", LastLineNo: 1}
+	if _, err := dao.Upsert(ctx, first, nil); err != nil {
+		t.Fatal(err)
+	}
+	second := &models.Log{ClientID: "c1", FileName: "a.log", LastLineNo: 2}
+	if _, err := dao.Upsert(ctx, second, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		got  time.Time
+	}{
+		{"created_at", second.CreatedAt},
+		{"updated_at", second.UpdatedAt},
+	}
+	for _, tt := range tests {
+		if tt.got.Location() != time.UTC {
+			t.Errorf("%s = %v, want UTC", tt.name, tt.got)
+		}
+	}
+	if !second.CreatedAt.Equal(first.CreatedAt) {
+		t.Errorf("re-upload changed created_at from %v to %v", first.CreatedAt, second.CreatedAt)
+	}
+	if !second.UpdatedAt.After(first.UpdatedAt) {
+		t.Errorf("re-upload kept updated_at at %v", second.UpdatedAt)
+	}
+
+	// The stored text carries the UTC designator, not a local offset
+	var stored struct{ CreatedAt, UpdatedAt string }
+	if err := db.Raw("SELECT created_at, updated_at FROM logs").Scan(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []string{stored.CreatedAt, stored.UpdatedAt} {
+		if !strings.HasSuffix(value, "Z") {
+			t.Errorf("stored timestamp %q is not UTC", value)
+		}
+	}
+}
+
+func TestLogDAOListModules(t *testing.T) {
+	dao, db := newTestLogDAO(t, false)
+	seeded := []models.Log{
+		{ClientID: "c1", FileName: "1.log", ModuleName: "editor"},
+		{ClientID: "c1", FileName: "2.log", ModuleName: "editor"},
+		{ClientID: "c1", FileName: "3.log", ModuleName: "agent"},
+		{ClientID: "c2", FileName: "1.log", ModuleName: "editor"},
+		{ClientID: "c2", FileName: "2.log", ModuleName: "completion"},
+		{ClientID: "c2", FileName: "3.log"},
+	}
+	if err := db.Create(&seeded).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		clientID string
+		want     []models.ModuleLogCount
+	}{
+		{"all clients", "", []models.ModuleLogCount{{ModuleName: "agent", Count: 1}, {ModuleName: "completion", Count: 1}, {ModuleName: "editor", Count: 3}}},
+		{"one client", "c1", []models.ModuleLogCount{{ModuleName: "agent", Count: 1}, {ModuleName: "editor", Count: 2}}},
+		{"unknown client", "c3", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := dao.ListModules(context.Background(), tt.clientID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListModules(%q) = %v, want %v", tt.clientID, got, tt.want)
+			}
+		})
+	}
+}
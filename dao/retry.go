@@ -0,0 +1,86 @@
+package dao
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/zgsm-ai/client-manager/internal"
+)
+
+// transientErrorMessages are fragments of driver errors that succeed on retry
+var transientErrorMessages = []string{
+	"database is locked",
+	"database table is locked",
+	"sqlite_busy",
+	"deadlock",
+	"connection reset",
+	"broken pipe",
+	"bad connection",
+}
+
+/**
+ * isTransient reports whether a database error is worth retrying
+ * @param {error} err - Error returned by gorm
+ * @returns {bool} True for connection churn, lock contention and deadlocks
+ */
+func isTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, fragment := range transientErrorMessages {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * withRetry runs a database operation, retrying transient failures
+ * @param {context.Context} ctx - Context for request cancellation
+ * @param {*logrus.Logger} log - Logger for retry attempts
+ * @param {string} op - Operation name used in logs
+ * @param {func() error} fn - Database operation
+ * @returns {error} nil on success, otherwise the last error
+ * @description
+ * - Makes up to database.retry.max_attempts attempts with exponential backoff
+ * - Non-transient errors are returned immediately
+ * - Never retries inside a transaction, the whole transaction is retried instead
+ * - Stops waiting when ctx is cancelled
+ */
+func withRetry(ctx context.Context, log *logrus.Logger, op string, fn func() error) error {
+	maxAttempts, backoff := internal.GetDBRetry()
+	if internal.TxFromContext(ctx) != nil {
+		maxAttempts = 1
+	}
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= maxAttempts || !isTransient(err) {
+			return err
+		}
+		log.WithError(err).WithFields(logrus.Fields{
+			"operation":    op,
+			"attempt":      attempt,
+			"max_attempts": maxAttempts,
+			"backoff":      backoff.String(),
+		}).Warn("Transient database error, retrying")
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
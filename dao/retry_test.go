@@ -0,0 +1,132 @@
+package dao
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/zgsm-ai/client-manager/internal"
+	"github.com/zgsm-ai/client-manager/internal/testutil"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"bad connection", driver.ErrBadConn, true},
+		{"wrapped bad connection", fmt.Errorf("query: %w", driver.ErrBadConn), true},
+		{"locked", errors.New("database is locked (5) (SQLITE_BUSY)"), true},
+		{"deadlock", errors.New("Deadlock found when trying to get lock"), true},
+		{"connection reset", errors.New("read tcp: connection reset by peer"), true},
+		{"not found", gorm.ErrRecordNotFound, false},
+		{"constraint", errors.New("UNIQUE constraint failed: logs.id"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransient(tt.err); got != tt.want {
+				t.Errorf("isTransient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	testutil.SetConfig(t, "database.retry.max_attempts", 3)
+	testutil.SetConfig(t, "database.retry.backoff", time.Millisecond)
+	log := testutil.NewLogger()
+	transient := errors.New("database is locked")
+	permanent := errors.New("no such table: logs")
+
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		errs         []error
+		wantAttempts int
+		wantErr      error
+	}{
+		{"fails once then succeeds", context.Background(), []error{transient}, 2, nil},
+		{"non-transient returns immediately", context.Background(), []error{permanent}, 1, permanent},
+		{"attempts exhausted", context.Background(), []error{transient, transient, transient, transient}, 3, transient},
+		{"no retry inside a transaction", internal.WithTx(context.Background(), &gorm.DB{}), []error{transient}, 1, transient},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			err := withRetry(tt.ctx, log, "test", func() error {
+				attempts++
+				if attempts <= len(tt.errs) {
+					return tt.errs[attempts-1]
+				}
+				return nil
+			})
+			if err != tt.wantErr {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestWithRetryStopsWhenCancelled(t *testing.T) {
+	testutil.SetConfig(t, "database.retry.max_attempts", 5)
+	testutil.SetConfig(t, "database.retry.backoff", time.Hour)
+	log := testutil.NewLogger()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	attempts := 0
+	err := withRetry(ctx, log, "test", func() error {
+		attempts++
+		return driver.ErrBadConn
+	})
+	if !errors.Is(err, driver.ErrBadConn) || attempts != 1 {
+		t.Errorf("got %v after %d attempts, want the first error without waiting", err, attempts)
+	}
+}
+
+func TestLogDAORetriesTransientQueryErrors(t *testing.T) {
+	testutil.SetConfig(t, "database.retry.max_attempts", 2)
+	testutil.SetConfig(t, "database.retry.backoff", time.Millisecond)
+	tests := []struct {
+		name     string
+		failures int
+		wantErr  bool
+	}{
+		{"recovers after one failure", 1, false},
+		{"gives up after max attempts", 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dao, db := newTestLogDAO(t, false)
+			seedLogs(t, db, 1)
+			failures := tt.failures
+			err := db.Callback().Query().Before("gorm:query").Register("test:fail", func(tx *gorm.DB) {
+				if failures > 0 {
+					failures--
+					_ = tx.AddError(driver.ErrBadConn)
+				}
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			log, err := dao.GetLog(context.Background(), "client-0", "app-0.log")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetLog error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (log == nil || log.FileName != "app-0.log") {
+				t.Errorf("GetLog = %+v, want app-0.log", log)
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package dao
+
+import "strings"
+
+// likeEscaper escapes the LIKE wildcards of a search term, '\' is the escape character
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+/**
+ * containsPattern builds a LIKE pattern matching values that contain a term
+ * @param {string} term - Search term, wildcards in it match literally
+ * @returns {string} Pattern for use with LIKE ? ESCAPE '\'
+ */
+func containsPattern(term string) string {
+	return "%" + likeEscaper.Replace(term) + "%"
+}
@@ -0,0 +1,45 @@
+package dao
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/zgsm-ai/client-manager/internal"
+)
+
+/**
+ * dbFromContext returns the database handle to use for an operation
+ * @param {context.Context} ctx - Context possibly carrying a transaction
+ * @param {*gorm.DB} db - Default database connection
+ * @returns {*gorm.DB} Transaction from the context, or db bound to ctx
+ */
+func dbFromContext(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx := internal.TxFromContext(ctx); tx != nil {
+		return tx
+	}
+	return db.WithContext(ctx)
+}
+
+/**
+ * runInTx runs fn inside a database transaction
+ * @param {context.Context} ctx - Context for request cancellation
+ * @param {*gorm.DB} db - Database connection
+ * @param {*logrus.Logger} log - Logger for retry attempts
+ * @param {func(context.Context) error} fn - Function performing the writes
+ * @returns {error} Error returned by fn or by the commit
+ * @description
+ * - fn receives a context carrying the transaction, DAOs called with it join the transaction
+ * - Nested calls use a savepoint of the outer transaction
+ * - Rolls back when fn returns an error or panics
+ * - An outermost transaction failing with a transient error is retried as a whole,
+ *   so fn must not have side effects outside the database
+ */
+func runInTx(ctx context.Context, db *gorm.DB, log *logrus.Logger, fn func(ctx context.Context) error) error {
+	return withRetry(ctx, log, "transaction", func() error {
+		return dbFromContext(ctx, db).Transaction(func(tx *gorm.DB) error {
+			return fn(internal.WithTx(ctx, tx))
+		})
+	})
+}
@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwksCache verifies token signatures, nil when auth.jwks_url is not configured
+var jwksCache *JWKSCache
+
+/**
+ * InitAuth initializes token verification
+ * @description
+ * - Creates the JWKS key cache when auth.jwks_url is configured
+ * - Without a JWKS URL tokens are parsed without signature verification
+ */
+func InitAuth() {
+	if url := GetJWKSURL(); url != "" {
+		jwksCache = NewJWKSCache(url, GetJWKSRefresh())
+	}
+}
+
+/**
+ * ParseTokenClaims extracts the claims of a bearer token
+ * @param {string} tokenString - Raw JWT, without the "Bearer " prefix
+ * @returns {jwt.MapClaims, error} Token claims and error if the token is invalid
+ * @description
+ * - Verifies the signature against the cached JWKS keys when configured
+ * - Falls back to unverified parsing otherwise
+ * @throws
+ * - Malformed token errors
+ * - Signature and expiry verification errors
+ */
+func ParseTokenClaims(tokenString string) (jwt.MapClaims, error) {
+	var token *jwt.Token
+	var err error
+	if jwksCache != nil {
+		token, err = jwt.Parse(tokenString, jwksCache.Keyfunc,
+			jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}))
+	} else {
+		token, _, err = jwt.NewParser().ParseUnverified(tokenString, jwt.MapClaims{})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, fmt.Errorf("unexpected token claims type")
+	}
+	return claims, nil
+}
@@ -1,6 +1,16 @@
 package internal
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -16,7 +26,7 @@ var AppConfig = &Config{}
 
 func InitFlags(rootCmd *cobra.Command) error {
 	// Add command line flags
-	rootCmd.Flags().StringVarP(&AppConfig.ListenAddr, "listen", "l", "", "Server listen address (e.g. :8080)")
+	rootCmd.Flags().StringVarP(&AppConfig.ListenAddr, "listen", "l", "", "Server listen address (e.g. :8080 or unix:///run/client-manager.sock)")
 	rootCmd.Flags().StringVarP(&AppConfig.ConfigPath, "config", "c", "", "Configuration file path")
 
 	return nil
@@ -26,15 +36,24 @@ func InitFlags(rootCmd *cobra.Command) error {
 // @returns {error} Error if configuration loading fails
 // @description
 // - Loads configuration from config.yaml file
+// - A --config file may be YAML, JSON or TOML, chosen by its extension (YAML without one)
 // - Merges environment variables
 // - Sets default values for missing configurations
+// - Compiles client_id.pattern once, later lookups reuse it
 // @throws
 // - Configuration file not found error
+// - Unsupported configuration file format error
 // - Configuration parsing error
+// - Invalid client_id.pattern error
 func LoadConfig(configPath string) error {
 	// If custom config path is provided, use it
 	if configPath != "" {
+		configType, err := configTypeFromPath(configPath)
+		if err != nil {
+			return err
+		}
 		viper.SetConfigFile(configPath)
+		viper.SetConfigType(configType)
 	} else {
 		viper.SetConfigName("config")
 		viper.SetConfigType("yaml")
@@ -44,23 +63,100 @@ func LoadConfig(configPath string) error {
 
 	// Set default values
 	viper.SetDefault("server.listen", ":8080")
+	viper.SetDefault("server.socket_mode", "0660")
+	viper.SetDefault("client_id.pattern", DefaultClientIDPattern)
+	viper.SetDefault("client_id.lowercase", false)
 	viper.SetDefault("database.dsn", "./data/client-manager.db")
+	viper.SetDefault("database.prepare_stmt", false)
+	viper.SetDefault("database.connect_retries", 0)
+	viper.SetDefault("database.connect_backoff", "1s")
+	viper.SetDefault("database.retry.max_attempts", 3)
+	viper.SetDefault("database.retry.backoff", "50ms")
+	viper.SetDefault("database.request_transactions", false)
+	viper.SetDefault("database.count_cache_ttl", "30s")
+	viper.SetDefault("pagination.default", 20)
+	viper.SetDefault("pagination.max", 100)
+	viper.SetDefault("pagination.log.default", 10)
+	viper.SetDefault("server.compression.enabled", false)
+	viper.SetDefault("server.compression.min_size", 1024)
+	viper.SetDefault("server.compression.content_types", []string{"application/json", "text/csv", "text/plain"})
+	viper.SetDefault("server.max_body_bytes", 0)
+	viper.SetDefault("server.request_timeout", "0s")
+	viper.SetDefault("server.max_concurrent", 0)
+	viper.SetDefault("server.retry_after", "1s")
+	viper.SetDefault("server.concurrency_exempt_paths", []string{"/healthz", "/live", "/ready", "/metrics"})
+	viper.SetDefault("uploads.request_timeout", "0s")
+	viper.SetDefault("uploads.max_body_bytes", 0)
+	viper.SetDefault("uploads.max_concurrent", 0)
+	viper.SetDefault("uploads.gzip.decompress", false)
+	viper.SetDefault("uploads.gzip.max_decompressed_bytes", 1<<30)
+	viper.SetDefault("storage.base_dir", "/data")
+	viper.SetDefault("storage.hash_subdirs", false)
+	viper.SetDefault("uploads.allowed_extensions", []string{".log", ".txt", ".json", ".gz"})
+	viper.SetDefault("uploads.retry_after", "1s")
+	viper.SetDefault("server.shutdown_timeout", "10s")
+	viper.SetDefault("log.retention.max_age", "0s")
+	viper.SetDefault("log.retention.interval", "24h")
+	viper.SetDefault("log.max_files_per_user", 0)
+	viper.SetDefault("admin.api_key", "")
+	viper.SetDefault("metrics.auth.username", "")
+	viper.SetDefault("metrics.auth.password", "")
+	viper.SetDefault("metrics.auth.token", "")
+	viper.SetDefault("auth.jwks_url", "")
+	viper.SetDefault("auth.jwks_refresh", "1h")
+	viper.SetDefault("auth.required", false)
+	viper.SetDefault("auth.exempt_paths", []string{"/healthz", "/live", "/ready", "/metrics", "/swagger"})
+	viper.SetDefault("cors.exempt_paths", []string{})
+	viper.SetDefault("swagger.enabled", true)
+	viper.SetDefault("swagger.path", "/swagger")
 	viper.SetDefault("log.level", "info")
+	viper.SetDefault("log.debug_bodies", false)
+	viper.SetDefault("log.debug_body_max_bytes", 2048)
 
 	// Enable environment variable override
 	viper.AutomaticEnv()
 
 	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			// Config file not found; create default config
-			return nil
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			return err
 		}
+		// Config file not found; run with the defaults
+	}
+
+	// Refuse to start rather than silently validating against another pattern
+	source := viper.GetString("client_id.pattern")
+	pattern, err := compileClientIDPattern(source)
+	if err != nil {
 		return err
 	}
+	clientIDPattern.Lock()
+	clientIDPattern.source, clientIDPattern.re = source, pattern
+	clientIDPattern.Unlock()
 
 	return nil
 }
 
+// supportedConfigTypes maps configuration file extensions to viper config types
+var supportedConfigTypes = map[string]string{
+	".yaml": "yaml",
+	".yml":  "yaml",
+	".json": "json",
+	".toml": "toml",
+}
+
+// configTypeFromPath returns the viper config type of a configuration file, YAML when it has no extension
+func configTypeFromPath(path string) (string, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == "" {
+		return "yaml", nil
+	}
+	configType, ok := supportedConfigTypes[ext]
+	if !ok {
+		return "", fmt.Errorf("unsupported configuration file format %q, use .yaml, .yml, .json or .toml", ext)
+	}
+	return configType, nil
+}
+
 // ApplyConfig applies command line overrides to the configuration
 func ApplyConfig() {
 	// Override listen address from command line if provided
@@ -76,3 +172,378 @@ func GetListenAddr() string {
 	}
 	return port
 }
+
+// GetDBPrepareStmt reports whether gorm should cache prepared statements
+func GetDBPrepareStmt() bool {
+	return viper.GetBool("database.prepare_stmt")
+}
+
+// CompressionConfig holds the response compression settings
+type CompressionConfig struct {
+	Enabled      bool
+	MinSize      int
+	ContentTypes []string
+}
+
+// GetCompressionConfig returns the response compression settings
+func GetCompressionConfig() CompressionConfig {
+	cfg := CompressionConfig{
+		Enabled:      viper.GetBool("server.compression.enabled"),
+		MinSize:      viper.GetInt("server.compression.min_size"),
+		ContentTypes: viper.GetStringSlice("server.compression.content_types"),
+	}
+	if cfg.MinSize < 0 {
+		cfg.MinSize = 0
+	}
+	return cfg
+}
+
+// UploadGzipConfig holds how gzip compressed uploads are inspected
+type UploadGzipConfig struct {
+	Decompress           bool
+	MaxDecompressedBytes int64
+}
+
+// GetUploadGzipConfig returns the gzip upload settings
+func GetUploadGzipConfig() UploadGzipConfig {
+	cfg := UploadGzipConfig{
+		Decompress:           viper.GetBool("uploads.gzip.decompress"),
+		MaxDecompressedBytes: viper.GetInt64("uploads.gzip.max_decompressed_bytes"),
+	}
+	if cfg.MaxDecompressedBytes <= 0 {
+		cfg.MaxDecompressedBytes = 1 << 30
+	}
+	return cfg
+}
+
+// GetMaxConcurrent returns the maximum number of requests processed concurrently, 0 means unlimited
+func GetMaxConcurrent() int {
+	n := viper.GetInt("server.max_concurrent")
+	if n < 0 {
+		n = 0
+	}
+	return n
+}
+
+// GetRetryAfter returns the Retry-After hint sent when server.max_concurrent is reached
+func GetRetryAfter() time.Duration {
+	d := viper.GetDuration("server.retry_after")
+	if d <= 0 {
+		d = time.Second
+	}
+	return d
+}
+
+// GetConcurrencyExemptPaths returns the path prefixes not subject to server.max_concurrent
+func GetConcurrencyExemptPaths() []string {
+	return viper.GetStringSlice("server.concurrency_exempt_paths")
+}
+
+// GetUploadMaxConcurrent returns the maximum number of concurrent log uploads, 0 means unlimited
+func GetUploadMaxConcurrent() int {
+	n := viper.GetInt("uploads.max_concurrent")
+	if n < 0 {
+		n = 0
+	}
+	return n
+}
+
+// GetUploadRetryAfter returns the Retry-After hint sent when the upload limit is reached
+func GetUploadRetryAfter() time.Duration {
+	d := viper.GetDuration("uploads.retry_after")
+	if d <= 0 {
+		d = time.Second
+	}
+	return d
+}
+
+// GetShutdownTimeout returns how long the server waits for in-flight requests on shutdown
+func GetShutdownTimeout() time.Duration {
+	d := viper.GetDuration("server.shutdown_timeout")
+	if d <= 0 {
+		d = 10 * time.Second
+	}
+	return d
+}
+
+// GetLogRetention returns the maximum age of log records and the purge interval, a zero age disables the purge
+func GetLogRetention() (maxAge, interval time.Duration) {
+	return viper.GetDuration("log.retention.max_age"), viper.GetDuration("log.retention.interval")
+}
+
+// GetDBConnectRetry returns how many times a failed database connection is retried and the initial backoff
+func GetDBConnectRetry() (retries int, backoff time.Duration) {
+	retries = viper.GetInt("database.connect_retries")
+	if retries < 0 {
+		retries = 0
+	}
+	backoff = viper.GetDuration("database.connect_backoff")
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	return retries, backoff
+}
+
+// GetAdminAPIKey returns the key required by admin endpoints, empty disables them
+func GetAdminAPIKey() string {
+	return viper.GetString("admin.api_key")
+}
+
+// GetJWKSURL returns the JWKS endpoint used to verify tokens, empty disables verification
+func GetJWKSURL() string {
+	return viper.GetString("auth.jwks_url")
+}
+
+// GetAuthRequired reports whether every request must carry a valid bearer token
+func GetAuthRequired() bool {
+	return viper.GetBool("auth.required")
+}
+
+// GetAuthExemptPaths returns the path prefixes served without a bearer token
+func GetAuthExemptPaths() []string {
+	return viper.GetStringSlice("auth.exempt_paths")
+}
+
+// GetCORSExemptPaths returns the path prefixes served without CORS headers
+func GetCORSExemptPaths() []string {
+	return viper.GetStringSlice("cors.exempt_paths")
+}
+
+// SwaggerConfig holds where the Swagger UI is served
+type SwaggerConfig struct {
+	Enabled bool
+	Path    string
+}
+
+// GetSwaggerConfig returns the Swagger UI settings, Path has a leading and no trailing slash
+func GetSwaggerConfig() SwaggerConfig {
+	path := strings.Trim(strings.TrimSpace(viper.GetString("swagger.path")), "/")
+	if path == "" {
+		path = "swagger"
+	}
+	return SwaggerConfig{
+		Enabled: viper.GetBool("swagger.enabled"),
+		Path:    "/" + path,
+	}
+}
+
+// GetJWKSRefresh returns the maximum age of cached JWKS keys
+func GetJWKSRefresh() time.Duration {
+	d := viper.GetDuration("auth.jwks_refresh")
+	if d <= 0 {
+		d = time.Hour
+	}
+	return d
+}
+
+// GetMaxBodyBytes returns the request body limit for all routes, 0 means unlimited
+func GetMaxBodyBytes() int64 {
+	return viper.GetInt64("server.max_body_bytes")
+}
+
+// GetUploadMaxBodyBytes returns the request body limit for log uploads, 0 means unlimited
+func GetUploadMaxBodyBytes() int64 {
+	return viper.GetInt64("uploads.max_body_bytes")
+}
+
+// GetDebugBodies returns whether request bodies are logged at debug level and how many bytes are logged
+func GetDebugBodies() (enabled bool, maxBytes int) {
+	maxBytes = viper.GetInt("log.debug_body_max_bytes")
+	if maxBytes <= 0 {
+		maxBytes = 2048
+	}
+	return viper.GetBool("log.debug_bodies"), maxBytes
+}
+
+// GetLogLevel returns the configured log level, info when unset or invalid
+func GetLogLevel() logrus.Level {
+	level, err := logrus.ParseLevel(viper.GetString("log.level"))
+	if err != nil {
+		return logrus.InfoLevel
+	}
+	return level
+}
+
+// GetDBRetry returns how many attempts a query gets on transient errors and the initial backoff
+func GetDBRetry() (maxAttempts int, backoff time.Duration) {
+	maxAttempts = viper.GetInt("database.retry.max_attempts")
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	backoff = viper.GetDuration("database.retry.backoff")
+	if backoff <= 0 {
+		backoff = 50 * time.Millisecond
+	}
+	return maxAttempts, backoff
+}
+
+// GetPageSizeLimits returns the default and maximum page size of a resource,
+// read from pagination.<resource>.default/max with pagination.default/max as fallback
+func GetPageSizeLimits(resource string) (defaultSize, maxSize int) {
+	maxSize = viper.GetInt("pagination." + resource + ".max")
+	if maxSize < 1 {
+		maxSize = viper.GetInt("pagination.max")
+	}
+	if maxSize < 1 {
+		maxSize = 100
+	}
+	defaultSize = viper.GetInt("pagination." + resource + ".default")
+	if defaultSize < 1 {
+		defaultSize = viper.GetInt("pagination.default")
+	}
+	if defaultSize < 1 {
+		defaultSize = 20
+	}
+	if defaultSize > maxSize {
+		defaultSize = maxSize
+	}
+	return defaultSize, maxSize
+}
+
+// GetCountCacheTTL returns how long list totals are reused for approximate counts, 0 disables caching
+func GetCountCacheTTL() time.Duration {
+	return viper.GetDuration("database.count_cache_ttl")
+}
+
+// GetDBRequestTransactions reports whether write endpoints run inside one transaction per request
+func GetDBRequestTransactions() bool {
+	return viper.GetBool("database.request_transactions")
+}
+
+// MetricsAuthConfig holds the credentials protecting the metrics endpoint
+type MetricsAuthConfig struct {
+	Username string
+	Password string
+	Token    string
+}
+
+// Enabled reports whether any credential is configured, a password alone still protects the endpoint
+func (c MetricsAuthConfig) Enabled() bool {
+	return c.basic() || c.Token != ""
+}
+
+// basic reports whether HTTP basic auth is configured
+func (c MetricsAuthConfig) basic() bool {
+	return c.Username != "" || c.Password != ""
+}
+
+// GetMetricsAuthConfig returns the metrics endpoint credentials, empty leaves the endpoint open
+func GetMetricsAuthConfig() MetricsAuthConfig {
+	return MetricsAuthConfig{
+		Username: viper.GetString("metrics.auth.username"),
+		Password: viper.GetString("metrics.auth.password"),
+		Token:    viper.GetString("metrics.auth.token"),
+	}
+}
+
+// GetSocketMode returns the permissions of the Unix socket when server.listen is a unix:// address
+func GetSocketMode() os.FileMode {
+	mode, err := strconv.ParseUint(viper.GetString("server.socket_mode"), 8, 32)
+	if err != nil {
+		return 0660
+	}
+	return os.FileMode(mode)
+}
+
+// GetUploadAllowedExtensions returns the lower-cased file extensions accepted for uploads, empty allows all
+func GetUploadAllowedExtensions() []string {
+	var exts []string
+	for _, ext := range viper.GetStringSlice("uploads.allowed_extensions") {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		exts = append(exts, ext)
+	}
+	return exts
+}
+
+// StorageConfig holds where uploaded log files are stored
+type StorageConfig struct {
+	BaseDir     string
+	HashSubdirs bool
+}
+
+// GetStorageConfig returns the log file storage settings
+func GetStorageConfig() StorageConfig {
+	cfg := StorageConfig{
+		BaseDir:     viper.GetString("storage.base_dir"),
+		HashSubdirs: viper.GetBool("storage.hash_subdirs"),
+	}
+	if cfg.BaseDir == "" {
+		cfg.BaseDir = "/data"
+	}
+	return cfg
+}
+
+// DefaultClientIDPattern is the client ID format used when client_id.pattern is unset or invalid
+const DefaultClientIDPattern = `^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`
+
+// ClientIDConfig holds how client IDs are normalized and validated
+type ClientIDConfig struct {
+	Pattern   *regexp.Regexp
+	Lowercase bool
+}
+
+// clientIDPattern caches the compiled client_id.pattern together with its source
+var clientIDPattern struct {
+	sync.Mutex
+	source string
+	re     *regexp.Regexp
+}
+
+// compileClientIDPattern compiles a client_id.pattern, an empty pattern selects DefaultClientIDPattern
+func compileClientIDPattern(source string) (*regexp.Regexp, error) {
+	if source == "" {
+		source = DefaultClientIDPattern
+	}
+	pattern, err := regexp.Compile(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client_id.pattern %q: %w", source, err)
+	}
+	return pattern, nil
+}
+
+// GetClientIDConfig returns the client ID settings, falling back to DefaultClientIDPattern
+// The pattern is only recompiled when client_id.pattern changes, an invalid one is logged once
+func GetClientIDConfig() ClientIDConfig {
+	source := viper.GetString("client_id.pattern")
+	clientIDPattern.Lock()
+	if clientIDPattern.re == nil || clientIDPattern.source != source {
+		pattern, err := compileClientIDPattern(source)
+		if err != nil {
+			logrus.WithError(err).Warnf("Falling back to the default client ID pattern %s", DefaultClientIDPattern)
+			pattern = regexp.MustCompile(DefaultClientIDPattern)
+		}
+		clientIDPattern.source, clientIDPattern.re = source, pattern
+	}
+	pattern := clientIDPattern.re
+	clientIDPattern.Unlock()
+
+	return ClientIDConfig{
+		Pattern:   pattern,
+		Lowercase: viper.GetBool("client_id.lowercase"),
+	}
+}
+
+// GetLogMaxFilesPerUser returns how many log files a user may keep, 0 means unlimited
+func GetLogMaxFilesPerUser() int {
+	max := viper.GetInt("log.max_files_per_user")
+	if max < 0 {
+		return 0
+	}
+	return max
+}
+
+// GetRequestTimeout returns the processing deadline of requests, 0 disables it
+func GetRequestTimeout() time.Duration {
+	return viper.GetDuration("server.request_timeout")
+}
+
+// GetUploadRequestTimeout returns the processing deadline of log uploads, 0 disables it
+func GetUploadRequestTimeout() time.Duration {
+	return viper.GetDuration("uploads.request_timeout")
+}
@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestLoadConfigFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr bool
+	}{
+		{"yaml", "config.yaml", "server:\n  listen: \":9001\"\n", false},
+		{"yml", "config.yml", "server:\n  listen: \":9001\"\n", false},
+		{"json", "config.json", `{"server": {"listen": ":9001"}}`, false},
+		{"toml", "config.toml", "[server]\nlisten = \":9001\"\n", false},
+		{"no extension is yaml", "config", "server:\n  listen: \":9001\"\n", false},
+		{"upper case extension", "CONFIG.JSON", `{"server": {"listen": ":9001"}}`, false},
+		{"unsupported format", "config.ini", "[server]\nlisten=:9001\n", true},
+		{"content not matching the extension", "config.json", "server:\n  listen: \":9001\"\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := LoadConfig(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadConfig error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := GetListenAddr(); got != ":9001" {
+				t.Errorf("server.listen = %q, want :9001", got)
+			}
+			// Defaults still apply to keys the file does not set
+			if got := GetStorageConfig().BaseDir; got != "/data" {
+				t.Errorf("storage.base_dir = %q, want the /data default", got)
+			}
+		})
+	}
+}
+
+func TestLoadConfigClientIDPattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{"default", "server:\n  listen: \":9001\"\n", DefaultClientIDPattern, false},
+		{"custom", "client_id:\n  pattern: \"^c[0-9]+$\"\n", "^c[0-9]+$", false},
+		{"invalid", "client_id:\n  pattern: \"^c[0-9+$\"\n", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := LoadConfig(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadConfig error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			first, second := GetClientIDConfig().Pattern, GetClientIDConfig().Pattern
+			if first.String() != tt.want {
+				t.Errorf("pattern = %q, want %q", first.String(), tt.want)
+			}
+			if first != second {
+				t.Error("pattern was compiled again for an unchanged client_id.pattern")
+			}
+		})
+	}
+}
+
+func TestGetClientIDConfigInvalidPattern(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("client_id.pattern", "^c[0-9+$")
+
+	if got := GetClientIDConfig().Pattern.String(); got != DefaultClientIDPattern {
+		t.Errorf("pattern = %q, want the default %q", got, DefaultClientIDPattern)
+	}
+}
@@ -1,7 +1,9 @@
 package internal
 
 import (
+	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/glebarez/sqlite"
@@ -15,14 +17,36 @@ import (
 // Global database instance
 var DB *gorm.DB
 
+// migratedModels lists the models whose tables are created by autoMigrate
+var migratedModels = []interface{}{
+	&models.Log{},
+	&models.AuditEntry{},
+}
+
+// txContextKey is the context key holding the active transaction
+type txContextKey struct{}
+
+// commitHooksContextKey is the context key holding the hooks of the request transaction
+type commitHooksContextKey struct{}
+
+// commitHooks collects functions to run once the request transaction has committed or rolled back
+type commitHooks struct {
+	mu          sync.Mutex
+	fns         []func()
+	rollbackFns []func()
+}
+
 /**
  * InitDB initializes the database connection
  * @returns {gorm.DB, error} Database connection and error if any
  * @description
  * - Creates SQLite database connection
+ * - Retries the connection database.connect_retries times with exponential backoff
  * - Auto-migrates database models
  * - Sets database connection pool settings
  * - Configures logging
+ * - Enables the prepared statement cache when database.prepare_stmt is set
+ * - Stores autoCreateTime/autoUpdateTime timestamps in UTC
  * @throws
  * - Database connection errors
  * - Migration errors
@@ -42,8 +66,12 @@ func InitDB() (*gorm.DB, error) {
 	)
 
 	// Connect to database
-	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
-		Logger: newLogger,
+	db, err := connectWithRetry(func() (*gorm.DB, error) {
+		return gorm.Open(sqlite.Open(dsn), &gorm.Config{
+			Logger:      newLogger,
+			PrepareStmt: GetDBPrepareStmt(),
+			NowFunc:     Now,
+		})
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
@@ -72,11 +100,52 @@ func InitDB() (*gorm.DB, error) {
 	return db, nil
 }
 
+/**
+ * Now returns the current time in UTC
+ * @returns {time.Time} Current UTC time
+ * @description
+ * - Single timestamp source for persisted times
+ */
+func Now() time.Time {
+	return time.Now().UTC()
+}
+
+/**
+ * connectWithRetry opens the database, retrying on failure
+ * @param {func() (*gorm.DB, error)} open - Function opening the database connection
+ * @returns {*gorm.DB, error} Database connection and the last error if all attempts fail
+ * @description
+ * - Makes 1 + database.connect_retries attempts
+ * - Doubles the wait between attempts starting from database.connect_backoff
+ * - Logs every failed attempt
+ */
+func connectWithRetry(open func() (*gorm.DB, error)) (*gorm.DB, error) {
+	retries, backoff := GetDBConnectRetry()
+	for attempt := 1; ; attempt++ {
+		db, err := open()
+		if err == nil {
+			return db, nil
+		}
+		if attempt > retries {
+			return nil, err
+		}
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"attempt":     attempt,
+			"max_retries": retries,
+			"backoff":     backoff.String(),
+		}).Warn("Database connection failed, retrying")
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
 /**
  * autoMigrate performs database migration for all models
  * @param {gorm.DB} db - Database connection
  * @returns {error} Error if migration fails
  * @description
+ * - Merges duplicate logs of databases created before idx_logs_client_file,
+ *   which would otherwise fail to build the unique index
  * - Migrates all defined models
  * - Creates tables if they don't exist
  * - Updates table structures if needed
@@ -84,9 +153,51 @@ func InitDB() (*gorm.DB, error) {
  * - Migration errors
  */
 func autoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(
-		&models.Log{},
-	)
+	migrator := db.Migrator()
+	if migrator.HasTable(&models.Log{}) && !migrator.HasIndex(&models.Log{}, "idx_logs_client_file") {
+		if err := mergeDuplicateLogs(db); err != nil {
+			return fmt.Errorf("failed to merge duplicate logs: %w", err)
+		}
+	}
+	return db.AutoMigrate(migratedModels...)
+}
+
+/**
+ * mergeDuplicateLogs collapses the logs sharing a client and file name into one record
+ * @param {gorm.DB} db - Database connection
+ * @returns {error} Error if the merge fails
+ * @description
+ * - Keeps the most recently updated record, the highest id on ties
+ * - The kept record covers the union of the line ranges of its duplicates
+ * - Runs in one transaction and is idempotent, so instances migrating the same
+ *   database concurrently agree on the result
+ * @throws
+ * - Database update and delete errors
+ */
+func mergeDuplicateLogs(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		merge := tx.Exec(`UPDATE logs SET
+				first_line_no = (SELECT MIN(d.first_line_no) FROM logs d WHERE d.client_id = logs.client_id AND d.file_name = logs.file_name),
+				last_line_no = (SELECT MAX(d.last_line_no) FROM logs d WHERE d.client_id = logs.client_id AND d.file_name = logs.file_name)
+			WHERE (client_id, file_name) IN (SELECT client_id, file_name FROM logs GROUP BY client_id, file_name HAVING COUNT(*) > 1)`)
+		if merge.Error != nil {
+			return merge.Error
+		}
+		if merge.RowsAffected == 0 {
+			return nil
+		}
+		removed := tx.Exec(`DELETE FROM logs WHERE EXISTS (SELECT 1 FROM logs d
+			WHERE d.client_id = logs.client_id AND d.file_name = logs.file_name
+			AND (d.updated_at > logs.updated_at OR (d.updated_at = logs.updated_at AND d.id > logs.id)))`)
+		if removed.Error != nil {
+			return removed.Error
+		}
+		logrus.WithFields(logrus.Fields{
+			"merged":  merge.RowsAffected,
+			"removed": removed.RowsAffected,
+		}).Warn("Merged duplicate log records")
+		return nil
+	})
 }
 
 /**
@@ -118,3 +229,103 @@ func CloseDB() error {
 	}
 	return nil
 }
+
+/**
+ * WithTx returns a context carrying a database transaction
+ * @param {context.Context} ctx - Parent context
+ * @param {*gorm.DB} tx - Active transaction
+ * @returns {context.Context} Context carrying the transaction
+ * @description
+ * - DAOs pick the transaction up so several writes share it
+ */
+func WithTx(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, txContextKey{}, tx)
+}
+
+/**
+ * TxFromContext returns the transaction carried by a context
+ * @param {context.Context} ctx - Context to inspect
+ * @returns {*gorm.DB} Active transaction, nil if none
+ */
+func TxFromContext(ctx context.Context) *gorm.DB {
+	if ctx == nil {
+		return nil
+	}
+	tx, _ := ctx.Value(txContextKey{}).(*gorm.DB)
+	return tx
+}
+
+/**
+ * withCommitHooks returns a context collecting AfterCommit functions
+ * @param {context.Context} ctx - Parent context
+ * @returns {context.Context, *commitHooks} Context carrying the hooks and the hooks to run after the commit
+ */
+func withCommitHooks(ctx context.Context) (context.Context, *commitHooks) {
+	hooks := &commitHooks{}
+	return context.WithValue(ctx, commitHooksContextKey{}, hooks), hooks
+}
+
+/**
+ * AfterCommit runs fn once the outermost transaction of the context has committed
+ * @param {context.Context} ctx - Context of the operation
+ * @param {func()} fn - Side effect outside the database, e.g. removing files
+ * @description
+ * - Inside a request transaction (see TxMiddleware) fn is deferred until it commits
+ *   and dropped when it rolls back
+ * - Otherwise fn runs immediately, callers invoke it after their own commit
+ */
+func AfterCommit(ctx context.Context, fn func()) {
+	hooks, _ := ctx.Value(commitHooksContextKey{}).(*commitHooks)
+	if hooks == nil {
+		fn()
+		return
+	}
+	hooks.mu.Lock()
+	defer hooks.mu.Unlock()
+	hooks.fns = append(hooks.fns, fn)
+}
+
+/**
+ * AfterRollback runs fn if the outermost transaction of the context rolls back
+ * @param {context.Context} ctx - Context of the operation
+ * @param {func()} fn - Cleanup outside the database, e.g. removing staged files
+ * @description
+ * - Inside a request transaction (see TxMiddleware) fn runs when it rolls back
+ *   or fails to commit, and is dropped when it commits
+ * - Otherwise fn never runs, the caller's own transaction has already committed
+ */
+func AfterRollback(ctx context.Context, fn func()) {
+	hooks, _ := ctx.Value(commitHooksContextKey{}).(*commitHooks)
+	if hooks == nil {
+		return
+	}
+	hooks.mu.Lock()
+	defer hooks.mu.Unlock()
+	hooks.rollbackFns = append(hooks.rollbackFns, fn)
+}
+
+/**
+ * run calls the functions collected by AfterCommit in registration order
+ */
+func (h *commitHooks) run() {
+	h.mu.Lock()
+	fns := h.fns
+	h.fns, h.rollbackFns = nil, nil
+	h.mu.Unlock()
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+/**
+ * rollback calls the functions collected by AfterRollback in registration order
+ */
+func (h *commitHooks) rollback() {
+	h.mu.Lock()
+	fns := h.rollbackFns
+	h.fns, h.rollbackFns = nil, nil
+	h.mu.Unlock()
+	for _, fn := range fns {
+		fn()
+	}
+}
@@ -0,0 +1,138 @@
+package internal
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/zgsm-ai/client-manager/internal/testutil"
+	"github.com/zgsm-ai/client-manager/models"
+)
+
+// newLegacyLogsDB opens a database whose logs table predates idx_logs_client_file
+func newLegacyLogsDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := testutil.NewDB(t, nil)
+	if err := db.AutoMigrate(&models.Log{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Migrator().DropIndex(&models.Log{}, "idx_logs_client_file"); err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := []models.Log{
+		{ClientID: "c1", FileName: "a.log", FirstLineNo: 10, LastLineNo: 20, UpdatedAt: base},
+		{ClientID: "c1", FileName: "a.log", FirstLineNo: 1, LastLineNo: 15, UpdatedAt: base.Add(time.Hour), UserID: "latest"},
+		{ClientID: "c1", FileName: "a.log", FirstLineNo: 5, LastLineNo: 30, UpdatedAt: base},
+		{ClientID: "c1", FileName: "b.log", FirstLineNo: 1, LastLineNo: 2, UpdatedAt: base},
+		{ClientID: "c2", FileName: "a.log", FirstLineNo: 3, LastLineNo: 4, UpdatedAt: base},
+	}
+	if err := db.Create(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// assertMergedLogs checks the logs left by newLegacyLogsDB after merging
+func assertMergedLogs(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	var logs []models.Log
+	if err := db.Order("client_id, file_name").Find(&logs).Error; err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		clientID, fileName, userID string
+		first, last                int64
+	}{
+		{"c1", "a.log", "latest", 1, 30},
+		{"c1", "b.log", "", 1, 2},
+		{"c2", "a.log", "", 3, 4},
+	}
+	if len(logs) != len(want) {
+		t.Fatalf("got %d logs, want %d", len(logs), len(want))
+	}
+	for i, w := range want {
+		got := logs[i]
+		if got.ClientID != w.clientID || got.FileName != w.fileName || got.UserID != w.userID ||
+			got.FirstLineNo != w.first || got.LastLineNo != w.last {
+			t.Errorf("log %d = %s/%s user %q lines %d-%d, want %s/%s user %q lines %d-%d", i,
+				got.ClientID, got.FileName, got.UserID, got.FirstLineNo, got.LastLineNo,
+				w.clientID, w.fileName, w.userID, w.first, w.last)
+		}
+	}
+}
+
+func TestAutoMigrateMergesDuplicateLogs(t *testing.T) {
+	db := newLegacyLogsDB(t)
+
+	if err := autoMigrate(db); err != nil {
+		t.Fatalf("autoMigrate: %v", err)
+	}
+	assertMergedLogs(t, db)
+	if !db.Migrator().HasIndex(&models.Log{}, "idx_logs_client_file") {
+		t.Errorf("unique index was not created")
+	}
+}
+
+func TestMergeDuplicateLogsConcurrently(t *testing.T) {
+	db := newLegacyLogsDB(t)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- mergeDuplicateLogs(db)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("mergeDuplicateLogs: %v", err)
+		}
+	}
+	assertMergedLogs(t, db)
+}
+
+func TestConnectWithRetry(t *testing.T) {
+	testutil.SetConfig(t, "database.connect_backoff", time.Millisecond)
+	tests := []struct {
+		name         string
+		retries      int
+		failures     int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{"first attempt succeeds", 3, 0, 1, false},
+		{"first attempt fails then succeeds", 3, 1, 2, false},
+		{"retries exhausted", 2, 5, 3, true},
+		{"retries disabled", 0, 1, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.SetConfig(t, "database.connect_retries", tt.retries)
+			attempts := 0
+			db, err := connectWithRetry(func() (*gorm.DB, error) {
+				attempts++
+				if attempts <= tt.failures {
+					return nil, errors.New("connection refused")
+				}
+				return testutil.NewDB(t, nil), nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if (db == nil) != tt.wantErr {
+				t.Errorf("db = %v, want a connection unless failing", db)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
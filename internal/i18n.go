@@ -0,0 +1,294 @@
+package internal
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultLanguage is used when the client accepts none of the supported languages
+const DefaultLanguage = "en"
+
+// Message IDs, stable keys of messageCatalog that survive rewording
+const (
+	MsgInternalError           = "internal.error"
+	MsgRequestTimeout          = "request.timeout"
+	MsgRequestInvalid          = "request.invalid"        // {error}
+	MsgBodyTooLarge            = "request.body_too_large" // {limit}
+	MsgRateLimited             = "request.rate_limited"
+	MsgTooManyRequests         = "request.too_many_concurrent"
+	MsgRouteNotFound           = "route.not_found"
+	MsgMethodNotAllowed        = "route.method_not_allowed"
+	MsgAuthHeaderRequired      = "auth.header_required"
+	MsgAuthHeaderNotBearer     = "auth.header_not_bearer"
+	MsgTokenRequired           = "auth.token_required"
+	MsgTokenInvalid            = "auth.token_invalid"
+	MsgBearerTokenRequired     = "auth.bearer_token_required"
+	MsgUserIDMismatch          = "auth.user_id_mismatch"
+	MsgAdminDisabled           = "admin.disabled"
+	MsgAdminKeyRequired        = "admin.key_required"
+	MsgMetricsAuthRequired     = "metrics.credentials_required"
+	MsgStorageUnavailable      = "storage.unavailable"
+	MsgFileReadFailed          = "file.read_failed"
+	MsgFileCreateFailed        = "file.create_failed"
+	MsgFileSaveFailed          = "file.save_failed"
+	MsgFieldRequired           = "field.required"        // {field}
+	MsgFieldRequiredString     = "field.required_string" // {field}
+	MsgFieldInvalid            = "field.invalid"         // {field}
+	MsgFieldDateFormat         = "field.date_format"     // {field}
+	MsgDateRangeInverted       = "date_range.inverted"
+	MsgFileExtensionNotAllowed = "file_name.extension_not_allowed" // {allowed}
+	MsgLogfileNameInvalid      = "logfile.name_invalid"
+	MsgLogfileGzipInvalid      = "logfile.gzip_invalid"
+	MsgLogfileTooLarge         = "logfile.decompressed_too_large"
+	MsgLogChanged              = "log.changed"
+	MsgFormatUnsupported       = "format.unsupported" // {format}
+	MsgFieldsUnknown           = "fields.unknown"     // {field}
+	MsgFieldsEmpty             = "fields.empty"
+	MsgSearchSourceUnknown     = "search.unknown_source" // {allowed}
+)
+
+// messageCatalog maps a language to the templates of the message IDs, {name} is replaced by a parameter
+var messageCatalog = map[string]map[string]string{
+	DefaultLanguage: {
+		MsgInternalError:           "Internal server error",
+		MsgRequestTimeout:          "Request timed out",
+		MsgRequestInvalid:          "{error}",
+		MsgBodyTooLarge:            "Request body exceeds the limit of {limit} bytes",
+		MsgRateLimited:             "Rate limit exceeded",
+		MsgTooManyRequests:         "Too many concurrent requests, please retry later",
+		MsgRouteNotFound:           "Route not found",
+		MsgMethodNotAllowed:        "Method not allowed",
+		MsgAuthHeaderRequired:      "Authorization header is required",
+		MsgAuthHeaderNotBearer:     "Authorization header must be Bearer token",
+		MsgTokenRequired:           "Token is required",
+		MsgTokenInvalid:            "Token is invalid",
+		MsgBearerTokenRequired:     "A valid bearer token is required",
+		MsgUserIDMismatch:          "userID is invalid",
+		MsgAdminDisabled:           "Admin API is disabled",
+		MsgAdminKeyRequired:        "Valid X-Admin-Key header is required",
+		MsgMetricsAuthRequired:     "Valid credentials are required to access metrics",
+		MsgStorageUnavailable:      "Log storage is unavailable",
+		MsgFileReadFailed:          "Failed to read file",
+		MsgFileCreateFailed:        "Failed to create file",
+		MsgFileSaveFailed:          "Failed to save file",
+		MsgFieldRequired:           "{field} is required",
+		MsgFieldRequiredString:     "{field} is required and must be a string",
+		MsgFieldInvalid:            "{field} is invalid",
+		MsgFieldDateFormat:         "{field} must be formatted as YYYY-MM-DD",
+		MsgDateRangeInverted:       "start_date must not be after end_date",
+		MsgFileExtensionNotAllowed: "file extension is not allowed, allowed: {allowed}",
+		MsgLogfileNameInvalid:      "file name is invalid",
+		MsgLogfileGzipInvalid:      "logfile is not valid gzip",
+		MsgLogfileTooLarge:         "decompressed logfile is too large",
+		MsgLogChanged:              "log has changed, re-sync and retry",
+		MsgFormatUnsupported:       "unsupported format: {format}",
+		MsgFieldsUnknown:           "unknown field: {field}",
+		MsgFieldsEmpty:             "fields must name at least one field",
+		MsgSearchSourceUnknown:     "types contains an unknown source, allowed: {allowed}",
+	},
+	"zh": {
+		MsgInternalError:           "服务器内部错误",
+		MsgRequestTimeout:          "请求超时",
+		MsgRequestInvalid:          "请求无效：{error}",
+		MsgBodyTooLarge:            "请求体超过 {limit} 字节的限制",
+		MsgRateLimited:             "请求频率超出限制",
+		MsgTooManyRequests:         "并发请求过多，请稍后重试",
+		MsgRouteNotFound:           "路由不存在",
+		MsgMethodNotAllowed:        "不支持的请求方法",
+		MsgAuthHeaderRequired:      "缺少 Authorization 请求头",
+		MsgAuthHeaderNotBearer:     "Authorization 请求头必须为 Bearer 令牌",
+		MsgTokenRequired:           "令牌不能为空",
+		MsgTokenInvalid:            "令牌无效",
+		MsgBearerTokenRequired:     "需要有效的 Bearer 令牌",
+		MsgUserIDMismatch:          "userID 无效",
+		MsgAdminDisabled:           "管理接口已禁用",
+		MsgAdminKeyRequired:        "需要有效的 X-Admin-Key 请求头",
+		MsgMetricsAuthRequired:     "访问监控指标需要有效的凭据",
+		MsgStorageUnavailable:      "日志存储不可用",
+		MsgFileReadFailed:          "读取文件失败",
+		MsgFileCreateFailed:        "创建文件失败",
+		MsgFileSaveFailed:          "保存文件失败",
+		MsgFieldRequired:           "{field} 不能为空",
+		MsgFieldRequiredString:     "{field} 不能为空且必须为字符串",
+		MsgFieldInvalid:            "{field} 无效",
+		MsgFieldDateFormat:         "{field} 必须为 YYYY-MM-DD 格式",
+		MsgDateRangeInverted:       "start_date 不能晚于 end_date",
+		MsgFileExtensionNotAllowed: "不允许的文件扩展名，允许：{allowed}",
+		MsgLogfileNameInvalid:      "文件名无效",
+		MsgLogfileGzipInvalid:      "logfile 不是有效的 gzip 文件",
+		MsgLogfileTooLarge:         "logfile 解压后过大",
+		MsgLogChanged:              "日志已变更，请重新同步后重试",
+		MsgFormatUnsupported:       "不支持的格式：{format}",
+		MsgFieldsUnknown:           "未知字段：{field}",
+		MsgFieldsEmpty:             "fields 至少需要包含一个字段",
+		MsgSearchSourceUnknown:     "types 包含未知的来源，允许：{allowed}",
+	},
+}
+
+/**
+ * Message is a localizable message
+ * @description
+ * - ID is a key of messageCatalog, Params fill the {name} placeholders of its template
+ * - String renders the English text, used for logs and Error methods
+ */
+type Message struct {
+	ID     string
+	Params map[string]string
+}
+
+/**
+ * NewMessage creates a localizable message
+ * @param {string} id - Message ID, one of the Msg* constants
+ * @param {...string} params - Placeholder names and values, alternating
+ * @returns {Message} Message to localize when responding
+ */
+func NewMessage(id string, params ...string) Message {
+	m := Message{ID: id}
+	if len(params) > 1 {
+		m.Params = make(map[string]string, len(params)/2)
+		for i := 0; i+1 < len(params); i += 2 {
+			m.Params[params[i]] = params[i+1]
+		}
+	}
+	return m
+}
+
+/**
+ * In renders the message in a language
+ * @param {string} lang - Language code, a key of messageCatalog
+ * @returns {string} Rendered text, English when the language lacks the ID, the ID when no language has it
+ */
+func (m Message) In(lang string) string {
+	template, ok := messageCatalog[lang][m.ID]
+	if !ok {
+		if template, ok = messageCatalog[DefaultLanguage][m.ID]; !ok {
+			return m.ID
+		}
+	}
+	for name, value := range m.Params {
+		template = strings.ReplaceAll(template, "{"+name+"}", value)
+	}
+	return template
+}
+
+/**
+ * String renders the message in English
+ * @returns {string} English text
+ */
+func (m Message) String() string {
+	return m.In(DefaultLanguage)
+}
+
+/**
+ * MessageError is an error carrying a localizable message
+ * @description
+ * - Lets helpers return errors that handlers localize before responding
+ */
+type MessageError struct {
+	Message Message
+}
+
+/**
+ * NewMessageError creates an error carrying a localizable message
+ * @param {string} id - Message ID, one of the Msg* constants
+ * @param {...string} params - Placeholder names and values, alternating
+ * @returns {*MessageError} Error whose text is the English message
+ */
+func NewMessageError(id string, params ...string) *MessageError {
+	return &MessageError{Message: NewMessage(id, params...)}
+}
+
+/**
+ * Error returns the English message
+ * @returns {string} Error message
+ */
+func (e *MessageError) Error() string {
+	return e.Message.String()
+}
+
+/**
+ * NegotiateLanguage picks the best supported language from an Accept-Language header
+ * @param {string} acceptLanguage - Accept-Language header value
+ * @returns {string} Supported language code, DefaultLanguage if none matches
+ * @description
+ * - Honours quality values, higher q wins, ties keep header order
+ * - Matches on the primary subtag, e.g. zh-CN selects zh
+ */
+func NegotiateLanguage(acceptLanguage string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{lang: strings.SplitN(tag, "-", 2)[0], q: q})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, c := range candidates {
+		if c.lang == DefaultLanguage {
+			return DefaultLanguage
+		}
+		if _, ok := messageCatalog[c.lang]; ok {
+			return c.lang
+		}
+	}
+	return DefaultLanguage
+}
+
+/**
+ * Localize renders a message in the language preferred by the client
+ * @param {string} acceptLanguage - Accept-Language header value
+ * @param {Message} message - Message to render
+ * @returns {string} Rendered text, English when no translation exists
+ */
+func Localize(acceptLanguage string, message Message) string {
+	return message.In(NegotiateLanguage(acceptLanguage))
+}
+
+/**
+ * Localized renders a message in the language preferred by the client of a request
+ * @param {*gin.Context} c - Gin context of the request
+ * @param {string} id - Message ID, one of the Msg* constants
+ * @param {...string} params - Placeholder names and values, alternating
+ * @returns {string} Rendered text
+ */
+func Localized(c *gin.Context, id string, params ...string) string {
+	return Localize(c.GetHeader("Accept-Language"), NewMessage(id, params...))
+}
+
+/**
+ * LocalizeError renders an error for the client
+ * @param {string} acceptLanguage - Accept-Language header value
+ * @param {error} err - Error to render
+ * @returns {string} Localized message of a MessageError, otherwise the error text
+ *   wrapped in MsgRequestInvalid, e.g. for binding errors
+ */
+func LocalizeError(acceptLanguage string, err error) string {
+	var msgErr *MessageError
+	if errors.As(err, &msgErr) {
+		return Localize(acceptLanguage, msgErr.Message)
+	}
+	return Localize(acceptLanguage, NewMessage(MsgRequestInvalid, "error", err.Error()))
+}
@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"sort"
+	"testing"
+)
+
+// messageIDs lists every Msg* constant, a new ID must be added here and to each language
+var messageIDs = []string{
+	MsgInternalError, MsgRequestTimeout, MsgRequestInvalid, MsgBodyTooLarge, MsgRateLimited,
+	MsgTooManyRequests, MsgRouteNotFound, MsgMethodNotAllowed, MsgAuthHeaderRequired,
+	MsgAuthHeaderNotBearer, MsgTokenRequired, MsgTokenInvalid, MsgBearerTokenRequired,
+	MsgUserIDMismatch, MsgAdminDisabled, MsgAdminKeyRequired, MsgMetricsAuthRequired,
+	MsgStorageUnavailable, MsgFileReadFailed, MsgFileCreateFailed, MsgFileSaveFailed,
+	MsgFieldRequired, MsgFieldRequiredString, MsgFieldInvalid, MsgFieldDateFormat,
+	MsgDateRangeInverted, MsgFileExtensionNotAllowed, MsgLogfileNameInvalid,
+	MsgLogfileGzipInvalid, MsgLogfileTooLarge, MsgLogChanged, MsgFormatUnsupported,
+	MsgFieldsUnknown, MsgFieldsEmpty, MsgSearchSourceUnknown,
+}
+
+// placeholderPattern matches the {name} placeholders of a template
+var placeholderPattern = regexp.MustCompile(`\{[a-z_]+\}`)
+
+// placeholders returns the sorted placeholders of a template
+func placeholders(template string) []string {
+	found := placeholderPattern.FindAllString(template, -1)
+	sort.Strings(found)
+	return found
+}
+
+func TestMessageCatalogCoversEveryID(t *testing.T) {
+	for lang, templates := range messageCatalog {
+		if len(templates) != len(messageIDs) {
+			t.Errorf("%s has %d templates, want %d", lang, len(templates), len(messageIDs))
+		}
+		for _, id := range messageIDs {
+			template, ok := templates[id]
+			if !ok {
+				t.Errorf("%s has no template for %s", lang, id)
+				continue
+			}
+			want := placeholders(messageCatalog[DefaultLanguage][id])
+			if got := placeholders(template); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s template for %s has placeholders %v, want %v", lang, id, got, want)
+			}
+		}
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		message        Message
+		want           string
+	}{
+		{"english", "en-US", NewMessage(MsgFieldRequired, "field", "q"), "q is required"},
+		{"chinese", "zh-CN,zh;q=0.9", NewMessage(MsgFieldRequired, "field", "q"), "q 不能为空"},
+		{"quality wins", "en;q=0.5,zh;q=0.8", NewMessage(MsgLogChanged), "日志已变更，请重新同步后重试"},
+		{"unsupported language", "fr", NewMessage(MsgTokenInvalid), "Token is invalid"},
+		{"dynamic parameter", "zh", NewMessage(MsgFileExtensionNotAllowed, "allowed", ".log, .txt"), "不允许的文件扩展名，允许：.log, .txt"},
+		{"unknown id", "zh", NewMessage("no.such.message"), "no.such.message"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Localize(tt.acceptLanguage, tt.message); got != tt.want {
+				t.Errorf("Localize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalizeError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"message error", NewMessageError(MsgFieldsUnknown, "field", "x"), "未知字段：x"},
+		{"binding error", errors.New("invalid syntax"), "请求无效：invalid syntax"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LocalizeError("zh", tt.err); got != tt.want {
+				t.Errorf("LocalizeError() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package internal
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+)
+
+// jwksMinRefreshInterval throttles refreshes triggered by unknown key ids
+const jwksMinRefreshInterval = 5 * time.Second
+
+/**
+ * JWKSCache caches the public keys of a remote JWKS endpoint
+ * @description
+ * - Keys are fetched lazily and refreshed once older than the refresh interval
+ * - An unknown key id forces a refresh to pick up rotated keys
+ * - Fetches are throttled to protect the JWKS endpoint, failed fetches back off
+ *   exponentially up to the refresh interval
+ * - Concurrent refreshes share one fetch, made without holding the key lock
+ * - Supports RSA and EC signing keys
+ */
+type JWKSCache struct {
+	url             string
+	refreshInterval time.Duration
+	minRefresh      time.Duration
+	client          *http.Client
+
+	group singleflight.Group
+
+	mu          sync.RWMutex
+	keys        map[string]interface{}
+	fetchedAt   time.Time
+	nextAttempt time.Time
+	failures    int
+	lastErr     error
+}
+
+// jsonWebKey is a single key of a JWKS document
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+/**
+ * NewJWKSCache creates a new JWKSCache instance
+ * @param {string} url - JWKS endpoint URL
+ * @param {time.Duration} refreshInterval - Maximum age of cached keys
+ * @returns {*JWKSCache} New JWKSCache instance
+ */
+func NewJWKSCache(url string, refreshInterval time.Duration) *JWKSCache {
+	return &JWKSCache{
+		url:             url,
+		refreshInterval: refreshInterval,
+		minRefresh:      jwksMinRefreshInterval,
+		client:          &http.Client{Timeout: 10 * time.Second},
+		keys:            map[string]interface{}{},
+	}
+}
+
+/**
+ * Keyfunc returns the verification key for a token, for use with jwt.Parse
+ * @param {*jwt.Token} token - Parsed, not yet verified token
+ * @returns {interface{}, error} Public key and error if no key matches
+ * @description
+ * - Refreshes stale keys before the lookup
+ * - Forces a refresh when the token's kid is unknown
+ * @throws
+ * - Unknown key id error
+ * - JWKS fetch errors when no usable keys are cached
+ */
+func (c *JWKSCache) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	c.mu.RLock()
+	stale := time.Since(c.fetchedAt) > c.refreshInterval
+	c.mu.RUnlock()
+	if stale {
+		if err := c.refresh(false); err != nil {
+			logrus.WithError(err).Warn("Failed to refresh JWKS, using cached keys")
+		}
+	}
+
+	if key, ok := c.lookup(kid); ok {
+		return key, nil
+	}
+
+	// Unknown kid: the signing key may have been rotated
+	if err := c.refresh(true); err != nil {
+		return nil, err
+	}
+	if key, ok := c.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id: %q", kid)
+}
+
+/**
+ * lookup finds a cached key by id
+ * @param {string} kid - Key id, empty matches the only cached key
+ * @returns {interface{}, bool} Public key and whether it was found
+ */
+func (c *JWKSCache) lookup(kid string) (interface{}, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if kid == "" && len(c.keys) == 1 {
+		for _, key := range c.keys {
+			return key, true
+		}
+	}
+	key, ok := c.keys[kid]
+	return key, ok
+}
+
+/**
+ * refresh fetches the JWKS document and replaces the cached keys
+ * @param {bool} force - Refresh even if the keys are not stale, subject to throttling
+ * @returns {error} Error if the keys cannot be fetched or parsed
+ * @description
+ * - Concurrent callers share one fetch, lookups keep using the cached keys meanwhile
+ * - No fetch is made before the next attempt is due, a throttled forced refresh
+ *   returns the error of the last failed fetch
+ */
+func (c *JWKSCache) refresh(force bool) error {
+	c.mu.RLock()
+	due := force || time.Since(c.fetchedAt) > c.refreshInterval
+	throttled := time.Now().Before(c.nextAttempt)
+	lastErr := c.lastErr
+	c.mu.RUnlock()
+	if !due {
+		return nil
+	}
+	if throttled {
+		if force {
+			return lastErr
+		}
+		return nil
+	}
+
+	_, err, _ := c.group.Do("jwks", func() (interface{}, error) {
+		// A fetch that just finished may have made this one unnecessary
+		c.mu.RLock()
+		throttled := time.Now().Before(c.nextAttempt)
+		lastErr := c.lastErr
+		c.mu.RUnlock()
+		if throttled {
+			return nil, lastErr
+		}
+
+		keys, err := c.fetch()
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		now := time.Now()
+		if err != nil {
+			c.failures++
+			c.lastErr = err
+			c.nextAttempt = now.Add(c.backoff())
+			return nil, err
+		}
+		c.keys = keys
+		c.fetchedAt = now
+		c.nextAttempt = now.Add(c.minRefresh)
+		c.failures = 0
+		c.lastErr = nil
+		return nil, nil
+	})
+	return err
+}
+
+/**
+ * backoff returns the delay before retrying after consecutive failed fetches
+ * @returns {time.Duration} minRefresh doubled per failure, capped at the refresh interval
+ */
+func (c *JWKSCache) backoff() time.Duration {
+	limit := c.refreshInterval
+	if limit < c.minRefresh {
+		limit = c.minRefresh
+	}
+	delay := c.minRefresh
+	for i := 1; i < c.failures && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		return limit
+	}
+	return delay
+}
+
+/**
+ * fetch downloads and parses the JWKS document
+ * @returns {map[string]interface{}, error} Public keys by key id and error if any
+ * @throws
+ * - HTTP request errors and non-200 responses
+ * - JSON decoding errors
+ */
+func (c *JWKSCache) fetch() (map[string]interface{}, error) {
+	resp, err := c.client.Get(c.url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: unexpected status %d", resp.StatusCode)
+	}
+
+	var doc struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(doc.Keys))
+	for _, jwk := range doc.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			logrus.WithError(err).WithField("kid", jwk.Kid).Warn("Skipping unusable JWKS key")
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+/**
+ * publicKey converts a JSON web key into a Go public key
+ * @returns {interface{}, error} *rsa.PublicKey or *ecdsa.PublicKey and error if unsupported
+ */
+func (k *jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBase64URLInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBase64URLInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve: %s", k.Crv)
+		}
+		x, err := decodeBase64URLInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBase64URLInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", k.Kty)
+	}
+}
+
+/**
+ * decodeBase64URLInt decodes a base64url encoded big-endian integer
+ * @param {string} s - Encoded value
+ * @returns {*big.Int, error} Decoded integer and error if the value is invalid
+ */
+func decodeBase64URLInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key parameter: %w", err)
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,178 @@
+package internal
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwksTestServer serves a JWKS document with the given key ids and counts fetches
+type jwksTestServer struct {
+	*httptest.Server
+	fetches atomic.Int32
+	mu      sync.Mutex
+	kids    []string
+	status  int
+	delay   time.Duration
+}
+
+// newJWKSTestServer starts a JWKS endpoint serving one RSA key per kid
+func newJWKSTestServer(t *testing.T, kids ...string) *jwksTestServer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &jwksTestServer{kids: kids, status: http.StatusOK}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+		s.mu.Lock()
+		kids, status, delay := s.kids, s.status, s.delay
+		s.mu.Unlock()
+		time.Sleep(delay)
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		keys := []jsonWebKey{}
+		for _, kid := range kids {
+			keys = append(keys, jsonWebKey{
+				Kty: "RSA",
+				Kid: kid,
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// set changes the served key ids and status
+func (s *jwksTestServer) set(status int, kids ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+	s.kids = kids
+}
+
+// tokenWithKid returns an unverified token carrying a key id
+func tokenWithKid(kid string) *jwt.Token {
+	return &jwt.Token{Header: map[string]interface{}{"kid": kid}}
+}
+
+func TestJWKSCacheVerifiesFromCache(t *testing.T) {
+	server := newJWKSTestServer(t, "k1")
+	cache := NewJWKSCache(server.URL, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		key, err := cache.Keyfunc(tokenWithKid("k1"))
+		if err != nil {
+			t.Fatalf("keyfunc: %v", err)
+		}
+		if _, ok := key.(*rsa.PublicKey); !ok {
+			t.Fatalf("key = %T, want *rsa.PublicKey", key)
+		}
+	}
+	if got := server.fetches.Load(); got != 1 {
+		t.Errorf("fetches = %d, want 1", got)
+	}
+}
+
+func TestJWKSCacheUnknownKid(t *testing.T) {
+	tests := []struct {
+		name        string
+		minRefresh  time.Duration
+		wantErr     bool
+		wantFetches int32
+	}{
+		{"rotated key is fetched", 0, false, 2},
+		{"refresh is throttled", time.Hour, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newJWKSTestServer(t, "k1")
+			cache := NewJWKSCache(server.URL, time.Hour)
+			cache.minRefresh = tt.minRefresh
+			if _, err := cache.Keyfunc(tokenWithKid("k1")); err != nil {
+				t.Fatalf("keyfunc: %v", err)
+			}
+
+			server.set(http.StatusOK, "k1", "k2")
+			_, err := cache.Keyfunc(tokenWithKid("k2"))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("keyfunc error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := server.fetches.Load(); got != tt.wantFetches {
+				t.Errorf("fetches = %d, want %d", got, tt.wantFetches)
+			}
+		})
+	}
+}
+
+func TestJWKSCacheBacksOffAfterFailure(t *testing.T) {
+	server := newJWKSTestServer(t, "k1")
+	server.set(http.StatusServiceUnavailable)
+	cache := NewJWKSCache(server.URL, time.Hour)
+	cache.minRefresh = time.Hour
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.Keyfunc(tokenWithKid("k1")); err == nil {
+			t.Fatalf("keyfunc succeeded while the JWKS endpoint is down")
+		}
+	}
+	if got := server.fetches.Load(); got != 1 {
+		t.Errorf("fetches during the outage = %d, want 1", got)
+	}
+}
+
+func TestJWKSCacheSharesConcurrentFetches(t *testing.T) {
+	server := newJWKSTestServer(t, "k1")
+	server.delay = 50 * time.Millisecond
+	cache := NewJWKSCache(server.URL, time.Hour)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cache.Keyfunc(tokenWithKid("k1")); err != nil {
+				t.Errorf("keyfunc: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := server.fetches.Load(); got != 1 {
+		t.Errorf("fetches = %d, want 1", got)
+	}
+}
+
+func TestJWKSCacheBackoff(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{3, 20 * time.Second},
+		{10, time.Minute},
+	}
+	for _, tt := range tests {
+		cache := NewJWKSCache("", time.Minute)
+		cache.failures = tt.failures
+		if got := cache.backoff(); got != tt.want {
+			t.Errorf("backoff after %d failures = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
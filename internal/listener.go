@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// unixScheme prefixes listen addresses that name a Unix domain socket
+const unixScheme = "unix://"
+
+/**
+ * Listen opens the listener for a server.listen address
+ * @param {string} addr - TCP address (e.g. :8080) or unix:///path/to.sock
+ * @returns {net.Listener, error} Listener and error if the address cannot be bound
+ * @description
+ * - TCP addresses are passed to net.Listen unchanged
+ * - A stale socket file left by a previous run is removed before binding
+ * - The socket file gets the server.socket_mode permissions
+ * - Closing the listener removes the socket file
+ * @throws
+ * - Refuses to remove an existing path that is not a socket
+ * - Bind and chmod errors
+ */
+func Listen(addr string) (net.Listener, error) {
+	if !strings.HasPrefix(addr, unixScheme) {
+		return net.Listen("tcp", addr)
+	}
+
+	path := strings.TrimPrefix(addr, unixScheme)
+	if path == "" {
+		return nil, fmt.Errorf("empty unix socket path in %q", addr)
+	}
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket %s: %w", path, err)
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, GetSocketMode()); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+	return ln, nil
+}
@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zgsm-ai/client-manager/internal/testutil"
+)
+
+// socketDir returns a short temporary directory, socket paths are limited to about 100 bytes
+func socketDir(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "cm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestListenUnixSocket(t *testing.T) {
+	testutil.SetConfig(t, "server.socket_mode", "0600")
+	path := filepath.Join(socketDir(t), "api.sock")
+	ln, err := Listen("unix://" + path)
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "pong")
+	})}
+	go func() { _ = server.Serve(ln) }()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSocket == 0 || info.Mode().Perm() != 0600 {
+		t.Errorf("socket mode = %v, want a socket with 0600", info.Mode())
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://unix/ping")
+	if err != nil {
+		t.Fatalf("request through the socket: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "pong" {
+		t.Errorf("response = %d %q, want 200 pong", resp.StatusCode, body)
+	}
+
+	if err := server.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket file left behind after close: %v", err)
+	}
+}
+
+func TestListenAddresses(t *testing.T) {
+	dir := socketDir(t)
+	stale := filepath.Join(dir, "stale.sock")
+	staleLn, err := net.Listen("unix", stale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Keep the file around as a crashed server would
+	staleLn.(*net.UnixListener).SetUnlinkOnClose(false)
+	staleLn.Close()
+	regular := filepath.Join(dir, "regular")
+	if err := os.WriteFile(regular, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		addr    string
+		network string
+		wantErr bool
+	}{
+		{"tcp", "127.0.0.1:0", "tcp", false},
+		{"new socket", "unix://" + filepath.Join(dir, "new.sock"), "unix", false},
+		{"stale socket is replaced", "unix://" + stale, "unix", false},
+		{"regular file is kept", "unix://" + regular, "", true},
+		{"empty path", "unix://", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln, err := Listen(tt.addr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Listen(%q) error = %v, want error %v", tt.addr, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer ln.Close()
+			if got := ln.Addr().Network(); got != tt.network {
+				t.Errorf("network = %s, want %s", got, tt.network)
+			}
+		})
+	}
+	if _, err := os.Stat(regular); err != nil {
+		t.Errorf("regular file was removed: %v", err)
+	}
+}
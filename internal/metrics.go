@@ -57,6 +57,42 @@ var (
 		},
 		[]string{"client_id", "module"},
 	)
+
+	// Stored log uploads counter, unlabeled to keep cardinality bounded
+	logUploadsTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "log_uploads_total",
+			Help: "Total number of log files stored",
+		},
+	)
+
+	// Stored log upload bytes counter
+	logUploadBytesTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "log_upload_bytes_total",
+			Help: "Total number of log file bytes stored",
+		},
+	)
+
+	// Rejected log uploads counter
+	logUploadsRejectedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "log_uploads_rejected_total",
+			Help: "Total number of rejected log uploads",
+		},
+		[]string{"reason"},
+	)
+)
+
+// Reasons for rejected log uploads
+const (
+	UploadRejectTooLarge     = "too_large"
+	UploadRejectBadRequest   = "bad_request"
+	UploadRejectUnauthorized = "unauthorized"
+	UploadRejectForbidden    = "forbidden"
+	UploadRejectInvalid      = "invalid"
+	UploadRejectUnavailable  = "storage_unavailable"
+	UploadRejectPrecondition = "precondition_failed"
 )
 
 /**
@@ -140,3 +176,22 @@ func RecordHTTPRequest(method, endpoint string, statusCode int, duration time.Du
 func RecordLogsReceived(clientID, module string) {
 	logsReceivedTotal.WithLabelValues(clientID, module).Inc()
 }
+
+/**
+ * RecordLogUpload records a stored log upload
+ * @param {int64} bytes - Number of bytes written to storage
+ * @description
+ * - Increments log_uploads_total and log_upload_bytes_total
+ */
+func RecordLogUpload(bytes int64) {
+	logUploadsTotal.Inc()
+	logUploadBytesTotal.Add(float64(bytes))
+}
+
+/**
+ * RecordLogUploadRejected records a rejected log upload
+ * @param {string} reason - One of the UploadReject* reasons
+ */
+func RecordLogUploadRejected(reason string) {
+	logUploadsRejectedTotal.WithLabelValues(reason).Inc()
+}
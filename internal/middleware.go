@@ -1,26 +1,41 @@
 package internal
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 /**
  * CORSMiddleware handles Cross-Origin Resource Sharing (CORS)
+ * @param {[]string} exempt - Path prefixes served without CORS headers
  * @description
  * - Adds CORS headers to the response
  * - Handles preflight requests
  * - Configures allowed origins, methods, and headers
  * @returns {gin.HandlerFunc} Gin middleware function
  */
-func CORSMiddleware() gin.HandlerFunc {
+func CORSMiddleware(exempt []string) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if IsExemptPath(c.Request.URL.Path, exempt) {
+			c.Next()
+			return
+		}
+
 		// Allow all origins for development
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Credentials", "true")
@@ -171,48 +186,73 @@ func PrometheusMiddleware() gin.HandlerFunc {
 }
 
 /**
- * TimeoutMiddleware adds timeout to requests
+ * TimeoutMiddleware bounds request processing time with a context deadline
+ * @param {time.Duration} timeout - Default timeout, 0 disables it
+ * @param {map[string]time.Duration} overrides - Timeouts per route, keyed by "METHOD /full/path"
  * @description
- * - Sets timeout for request processing
- * - Cancels context if timeout is exceeded
- * - Prevents long-running requests
- * @param {time.Duration} timeout - Request timeout duration
+ * - Runs the handler synchronously with a deadline on the request context
+ * - Handlers must pass c.Request.Context() to the DAOs to be cancelled
+ * - Returns 504 when the deadline passed and the handler wrote nothing
+ * - Must be registered globally so that overrides can raise the default timeout
  * @returns {gin.HandlerFunc} Gin middleware function
  */
-func TimeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+func TimeoutMiddleware(timeout time.Duration, overrides map[string]time.Duration) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Create context with timeout
-		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
-		defer cancel()
+		d := timeout
+		if override, ok := overrides[c.Request.Method+" "+c.FullPath()]; ok {
+			d = override
+		}
+		if d <= 0 {
+			c.Next()
+			return
+		}
 
 		// Replace request context
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
 		c.Request = c.Request.WithContext(ctx)
 
-		// Create channel to monitor completion
-		done := make(chan struct{})
-
-		// Process request in goroutine
-		go func() {
-			c.Next()
-			close(done)
-		}()
+		c.Next()
 
-		// Wait for completion or timeout
-		select {
-		case <-done:
-			// Request completed normally
-			return
-		case <-ctx.Done():
-			// Timeout occurred
-			c.AbortWithStatusJSON(http.StatusRequestTimeout, gin.H{
-				"code":    "timeout.error",
-				"message": "Request timed out",
-			})
-			return
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !c.Writer.Written() {
+			GatewayTimeout(c)
 		}
 	}
 }
 
+/**
+ * GatewayTimeout aborts the request with the standard 504 error response
+ * @param {*gin.Context} c - Gin context
+ */
+func GatewayTimeout(c *gin.Context) {
+	c.AbortWithStatusJSON(http.StatusGatewayTimeout, gin.H{
+		"code":    "timeout.error",
+		"message": Localized(c, MsgRequestTimeout),
+	})
+}
+
+/**
+ * RouteNotFound responds with the standard 404 error for unknown routes
+ * @param {*gin.Context} c - Gin context
+ */
+func RouteNotFound(c *gin.Context) {
+	c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+		"code":    "notfound.error",
+		"message": Localized(c, MsgRouteNotFound),
+	})
+}
+
+/**
+ * MethodNotAllowed responds with the standard 405 error for a known route requested with another method
+ * @param {*gin.Context} c - Gin context
+ */
+func MethodNotAllowed(c *gin.Context) {
+	c.AbortWithStatusJSON(http.StatusMethodNotAllowed, gin.H{
+		"code":    "method_not_allowed",
+		"message": Localized(c, MsgMethodNotAllowed),
+	})
+}
+
 /**
  * RateLimitMiddleware implements rate limiting
  * @description
@@ -255,7 +295,7 @@ func RateLimitMiddleware(requests int, window time.Duration) gin.HandlerFunc {
 		if record.count >= requests {
 			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
 				"code":    "rate_limit.exceeded",
-				"message": "Rate limit exceeded",
+				"message": Localized(c, MsgRateLimited),
 			})
 			return
 		}
@@ -267,23 +307,51 @@ func RateLimitMiddleware(requests int, window time.Duration) gin.HandlerFunc {
 	}
 }
 
+/**
+ * IsExemptPath reports whether a request path falls under one of the exempt prefixes
+ * @param {string} path - Request path
+ * @param {[]string} prefixes - Exempt path prefixes, e.g. /swagger
+ * @returns {bool} True when path equals a prefix or lies below it
+ * @description
+ * - Matches whole path segments, /metrics does not exempt /metricsx
+ */
+func IsExemptPath(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		prefix = strings.TrimSuffix(strings.TrimSpace(prefix), "/")
+		if prefix == "" {
+			continue
+		}
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
 /**
  * AuthMiddleware handles authentication
+ * @param {[]string} exempt - Path prefixes served without a token
  * @description
  * - Validates authentication token
  * - Extracts user information from token
  * - Adds user information to context
  * - Returns 401 if authentication fails
+ * - Skips exempt paths such as health checks and metrics
  * @returns {gin.HandlerFunc} Gin middleware function
  */
-func AuthMiddleware() gin.HandlerFunc {
+func AuthMiddleware(exempt []string) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if IsExemptPath(c.Request.URL.Path, exempt) {
+			c.Next()
+			return
+		}
+
 		// Get authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"code":    "auth.missing",
-				"message": "Authorization header is required",
+				"message": Localized(c, MsgAuthHeaderRequired),
 			})
 			return
 		}
@@ -292,7 +360,7 @@ func AuthMiddleware() gin.HandlerFunc {
 		if !strings.HasPrefix(authHeader, "Bearer ") {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"code":    "auth.invalid_format",
-				"message": "Authorization header must be Bearer token",
+				"message": Localized(c, MsgAuthHeaderNotBearer),
 			})
 			return
 		}
@@ -302,15 +370,22 @@ func AuthMiddleware() gin.HandlerFunc {
 		if token == "" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"code":    "auth.empty_token",
-				"message": "Token is required",
+				"message": Localized(c, MsgTokenRequired),
 			})
 			return
 		}
 
-		// Validate token (in a real implementation, this would validate JWT or similar)
-		// For simplicity, we'll just check if token is not empty
-		// In production, you should implement proper token validation
-		userID := "user_" + token // Simplified user extraction
+		// Validate token, the signature is verified when a JWKS URL is configured
+		claims, err := ParseTokenClaims(token)
+		id, ok := claims["id"]
+		if err != nil || !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"code":    "auth.invalid_token",
+				"message": Localized(c, MsgTokenInvalid),
+			})
+			return
+		}
+		userID := fmt.Sprint(id)
 
 		// Add user information to context
 		c.Set("user_id", userID)
@@ -345,7 +420,7 @@ func RecoveryMiddleware() gin.HandlerFunc {
 		// Return error response
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"code":    "internal.error",
-			"message": "Internal server error",
+			"message": Localized(c, MsgInternalError),
 		})
 	})
 }
@@ -371,3 +446,565 @@ func SetSecurityHeaders() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+/**
+ * GzipMiddleware compresses responses with gzip
+ * @param {int} minSize - Minimum response size in bytes before compression kicks in
+ * @param {[]string} contentTypes - Content types eligible for compression
+ * @description
+ * - Only compresses when the client sends Accept-Encoding: gzip
+ * - Buffers the response until minSize is reached, small responses are sent as is
+ * - Skips responses that already carry a Content-Encoding (e.g. gzipped log files)
+ * - Skips partial content responses and content types outside the allowlist
+ * @returns {gin.HandlerFunc} Gin middleware function
+ */
+func GzipMiddleware(minSize int, contentTypes []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodHead || !acceptsGzip(c.Request.Header.Get("Accept-Encoding")) {
+			c.Next()
+			return
+		}
+
+		gw := &gzipResponseWriter{
+			ResponseWriter: c.Writer,
+			minSize:        minSize,
+			contentTypes:   contentTypes,
+		}
+		c.Writer = gw
+		defer func() {
+			gw.finish()
+			c.Writer = gw.ResponseWriter
+		}()
+
+		c.Next()
+	}
+}
+
+/**
+ * acceptsGzip checks whether an Accept-Encoding header allows gzip
+ * @param {string} acceptEncoding - Accept-Encoding header value
+ * @returns {bool} True if gzip is accepted
+ */
+func acceptsGzip(acceptEncoding string) bool {
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		if strings.TrimSpace(fields[0]) != "gzip" {
+			continue
+		}
+		for _, param := range fields[1:] {
+			if strings.ReplaceAll(strings.TrimSpace(param), " ", "") == "q=0" {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// gzipResponseWriter decides per response whether to compress the body
+type gzipResponseWriter struct {
+	gin.ResponseWriter
+	minSize      int
+	contentTypes []string
+	decided      bool
+	compress     bool
+	buf          []byte
+	gz           *gzip.Writer
+}
+
+/**
+ * eligible checks whether the response headers allow compression
+ * @returns {bool} True if the response may be compressed
+ */
+func (w *gzipResponseWriter) eligible() bool {
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+	switch w.Status() {
+	case http.StatusNoContent, http.StatusNotModified, http.StatusPartialContent:
+		return false
+	}
+	if cl := header.Get("Content-Length"); cl != "" {
+		if n, err := strconv.Atoi(cl); err == nil && n < w.minSize {
+			return false
+		}
+	}
+	contentType := strings.TrimSpace(strings.Split(header.Get("Content-Type"), ";")[0])
+	for _, allowed := range w.contentTypes {
+		if strings.EqualFold(contentType, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * startGzip switches the response to gzip encoding and flushes buffered data
+ * @returns {error} Error if writing the buffered data fails
+ */
+func (w *gzipResponseWriter) startGzip() error {
+	w.decided, w.compress = true, true
+	header := w.Header()
+	header.Set("Content-Encoding", "gzip")
+	header.Add("Vary", "Accept-Encoding")
+	header.Del("Content-Length")
+	w.gz = gzip.NewWriter(w.ResponseWriter)
+	buf := w.buf
+	w.buf = nil
+	_, err := w.gz.Write(buf)
+	return err
+}
+
+/**
+ * passthrough sends the response uncompressed and flushes buffered data
+ * @returns {error} Error if writing the buffered data fails
+ */
+func (w *gzipResponseWriter) passthrough() error {
+	w.decided = true
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// Write buffers data until the compression decision can be made
+func (w *gzipResponseWriter) Write(data []byte) (int, error) {
+	if !w.decided && !w.eligible() {
+		if err := w.passthrough(); err != nil {
+			return 0, err
+		}
+	}
+	if w.decided {
+		if w.compress {
+			return w.gz.Write(data)
+		}
+		return w.ResponseWriter.Write(data)
+	}
+
+	w.buf = append(w.buf, data...)
+	if len(w.buf) >= w.minSize {
+		if err := w.startGzip(); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+// WriteString writes a string through the compression decision logic
+func (w *gzipResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Written reports whether any body data has been produced
+func (w *gzipResponseWriter) Written() bool {
+	return len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+// Flush forces buffered data out, compressing it if already decided
+func (w *gzipResponseWriter) Flush() {
+	if !w.decided {
+		_ = w.passthrough()
+	}
+	if w.compress {
+		_ = w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+/**
+ * finish completes the response after the handler chain has run
+ * @description
+ * - Sends small buffered responses uncompressed
+ * - Closes the gzip stream for compressed responses
+ */
+func (w *gzipResponseWriter) finish() {
+	if !w.decided {
+		_ = w.passthrough()
+	}
+	if w.compress {
+		_ = w.gz.Close()
+	}
+}
+
+/**
+ * ConcurrencyLimitMiddleware limits the number of requests processed concurrently
+ * @param {int} max - Maximum number of in-flight requests
+ * @param {time.Duration} retryAfter - Retry-After hint returned to rejected clients
+ * @param {[]string} exempt - Path prefixes never limited nor counted, e.g. health checks
+ * @description
+ * - Uses a buffered channel as a semaphore
+ * - Rejects requests immediately with 503 when the limit is reached
+ * - Sets the Retry-After header (in seconds) on rejection
+ * @returns {gin.HandlerFunc} Gin middleware function
+ */
+func ConcurrencyLimitMiddleware(max int, retryAfter time.Duration, exempt []string) gin.HandlerFunc {
+	sem := make(chan struct{}, max)
+	retrySeconds := strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))
+
+	return func(c *gin.Context) {
+		if IsExemptPath(c.Request.URL.Path, exempt) {
+			c.Next()
+			return
+		}
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+			c.Next()
+		default:
+			c.Header("Retry-After", retrySeconds)
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"code":    "service.busy",
+				"message": Localized(c, MsgTooManyRequests),
+			})
+		}
+	}
+}
+
+/**
+ * AdminAuthMiddleware restricts access to administrators
+ * @param {string} apiKey - Admin API key, empty disables admin endpoints
+ * @description
+ * - Expects the key in the X-Admin-Key header
+ * - Returns 403 when no admin key is configured
+ * - Returns 401 when the key is missing or wrong
+ * - Compares keys in constant time
+ * @returns {gin.HandlerFunc} Gin middleware function
+ */
+func AdminAuthMiddleware(apiKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if apiKey == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"code":    "auth.admin_disabled",
+				"message": Localized(c, MsgAdminDisabled),
+			})
+			return
+		}
+		key := c.GetHeader("X-Admin-Key")
+		if key == "" || subtle.ConstantTimeCompare([]byte(key), []byte(apiKey)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"code":    "auth.invalid_admin_key",
+				"message": Localized(c, MsgAdminKeyRequired),
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+/**
+ * BodyLimitMiddleware limits the size of request bodies
+ * @param {int64} defaultLimit - Body limit in bytes for all routes, 0 means unlimited
+ * @param {map[string]int64} overrides - Limits per route, keyed by "METHOD /full/path"
+ * @description
+ * - Rejects requests whose Content-Length exceeds the limit with 413 immediately
+ * - Wraps the body with http.MaxBytesReader for chunked or lying clients,
+ *   handlers map the resulting *http.MaxBytesError to 413 with RequestTooLarge
+ * - Must be registered globally so that overrides can raise the default limit
+ * @returns {gin.HandlerFunc} Gin middleware function
+ */
+func BodyLimitMiddleware(defaultLimit int64, overrides map[string]int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := defaultLimit
+		if override, ok := overrides[c.Request.Method+" "+c.FullPath()]; ok {
+			limit = override
+		}
+		if limit <= 0 || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > limit {
+			RequestTooLarge(c, limit)
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
+
+/**
+ * RequestTooLarge aborts the request with the standard 413 error response
+ * @param {*gin.Context} c - Gin context
+ * @param {int64} limit - Body limit in bytes that was exceeded
+ */
+func RequestTooLarge(c *gin.Context, limit int64) {
+	c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+		"code":    "request.too_large",
+		"message": Localized(c, MsgBodyTooLarge, "limit", strconv.FormatInt(limit, 10)),
+	})
+}
+
+// redactedHeaders lists the request headers never written to debug logs
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"X-Admin-Key":         true,
+}
+
+/**
+ * DebugBodyMiddleware logs request bodies for debugging client integrations
+ * @param {int} maxBytes - Maximum number of body bytes written to the log
+ * @description
+ * - Logs method, path, headers and the truncated body at debug level
+ * - Redacts authentication headers
+ * - Only reads the logged prefix, the handler receives the complete body
+ * - Skipped when the logger is not at debug level, see log.level
+ * @returns {gin.HandlerFunc} Gin middleware function
+ */
+func DebugBodyMiddleware(maxBytes int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logEntry := logrus.NewEntry(logrus.StandardLogger())
+		if logger, exists := c.Get("logger"); exists {
+			logEntry = logger.(*logrus.Entry)
+		}
+		if !logEntry.Logger.IsLevelEnabled(logrus.DebugLevel) {
+			c.Next()
+			return
+		}
+
+		var prefix []byte
+		truncated := false
+		if c.Request.Body != nil {
+			body := c.Request.Body
+			var err error
+			prefix, err = io.ReadAll(io.LimitReader(body, int64(maxBytes)+1))
+			if err != nil {
+				logEntry.WithError(err).Debug("Failed to read request body for logging")
+			}
+			// Hand the consumed prefix back to the handler, followed by the rest
+			c.Request.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(prefix), body), body}
+			if len(prefix) > maxBytes {
+				prefix = prefix[:maxBytes]
+				truncated = true
+			}
+		}
+
+		headers := make(map[string]string, len(c.Request.Header))
+		for name, values := range c.Request.Header {
+			if redactedHeaders[name] {
+				headers[name] = "[REDACTED]"
+				continue
+			}
+			headers[name] = strings.Join(values, ", ")
+		}
+
+		logEntry.WithFields(logrus.Fields{
+			"method":    c.Request.Method,
+			"path":      c.Request.URL.Path,
+			"headers":   headers,
+			"body":      string(prefix),
+			"truncated": truncated,
+		}).Debug("HTTP request body")
+
+		c.Next()
+	}
+}
+
+/**
+ * MetricsAuthMiddleware protects the metrics endpoint
+ * @param {MetricsAuthConfig} cfg - Basic auth and/or bearer token credentials
+ * @description
+ * - Accepts HTTP basic auth when a username or password is configured
+ * - Accepts a bearer token when a token is configured
+ * - Returns 401 with a WWW-Authenticate challenge otherwise
+ * - Compares credentials in constant time
+ * - Passes every request through when no credential is configured
+ * @returns {gin.HandlerFunc} Gin middleware function
+ */
+func MetricsAuthMiddleware(cfg MetricsAuthConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.Enabled() {
+			c.Next()
+			return
+		}
+		if cfg.basic() {
+			if username, password, ok := c.Request.BasicAuth(); ok &&
+				subtle.ConstantTimeCompare([]byte(username), []byte(cfg.Username)) == 1 &&
+				subtle.ConstantTimeCompare([]byte(password), []byte(cfg.Password)) == 1 {
+				c.Next()
+				return
+			}
+		}
+		if cfg.Token != "" {
+			authHeader := c.GetHeader("Authorization")
+			if strings.HasPrefix(authHeader, "Bearer ") &&
+				subtle.ConstantTimeCompare([]byte(authHeader[7:]), []byte(cfg.Token)) == 1 {
+				c.Next()
+				return
+			}
+		}
+
+		if cfg.basic() {
+			c.Header("WWW-Authenticate", `Basic realm="metrics"`)
+		} else {
+			c.Header("WWW-Authenticate", `Bearer realm="metrics"`)
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"code":    "auth.unauthorized",
+			"message": Localized(c, MsgMetricsAuthRequired),
+		})
+	}
+}
+
+/**
+ * TxMiddleware runs the rest of the handler chain inside a database transaction
+ * @param {*gorm.DB} db - Database connection
+ * @param {*logrus.Logger} log - Logger for commit and rollback failures
+ * @description
+ * - Puts the transaction on the request context, DAOs called with it join the transaction
+ * - Commits when the handler responds with 2xx and recorded no errors
+ * - Rolls back on any other status, on handler errors and while a panic unwinds
+ * - Buffers the response until the transaction has ended, a failed commit answers 500
+ *   instead of acknowledging writes that were lost, so only use it for small responses
+ * - Side effects registered with AfterCommit run after the commit and are dropped on rollback,
+ *   cleanups registered with AfterRollback run after a rollback or a failed commit
+ * @returns {gin.HandlerFunc} Gin middleware function
+ */
+func TxMiddleware(db *gorm.DB, log *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tx := db.WithContext(c.Request.Context()).Begin()
+		if tx.Error != nil {
+			log.WithError(tx.Error).Error("Failed to begin request transaction")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"code":    "internal.error",
+				"message": Localized(c, MsgInternalError),
+			})
+			return
+		}
+		ctx, hooks := withCommitHooks(WithTx(c.Request.Context(), tx))
+		c.Request = c.Request.WithContext(ctx)
+
+		w := newBufferedResponseWriter(c.Writer)
+		c.Writer = w
+		committed := false
+		defer func() {
+			c.Writer = w.ResponseWriter
+			if committed {
+				return
+			}
+			if err := tx.Rollback().Error; err != nil {
+				log.WithError(err).Warn("Failed to roll back request transaction")
+			}
+			hooks.rollback()
+		}()
+
+		c.Next()
+
+		status := w.Status()
+		if status < 200 || status >= 300 || len(c.Errors) > 0 {
+			w.flush()
+			return
+		}
+		if err := tx.Commit().Error; err != nil {
+			log.WithError(err).WithFields(logrus.Fields{
+				"method": c.Request.Method,
+				"path":   c.FullPath(),
+			}).Error("Failed to commit request transaction")
+			w.discard()
+			c.Writer = w.ResponseWriter
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"code":    "internal.error",
+				"message": Localized(c, MsgInternalError),
+			})
+			return
+		}
+		committed = true
+		w.flush()
+		hooks.run()
+	}
+}
+
+// bufferedResponseWriter holds back the status and body of a response until flush
+type bufferedResponseWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+	header http.Header
+}
+
+/**
+ * newBufferedResponseWriter wraps a response writer
+ * @param {gin.ResponseWriter} w - Writer receiving the response on flush
+ * @returns {*bufferedResponseWriter} Buffering writer, remembers the headers set so far
+ */
+func newBufferedResponseWriter(w gin.ResponseWriter) *bufferedResponseWriter {
+	return &bufferedResponseWriter{ResponseWriter: w, header: w.Header().Clone()}
+}
+
+// WriteHeader records the status code
+func (w *bufferedResponseWriter) WriteHeader(code int) {
+	if code > 0 {
+		w.status = code
+	}
+}
+
+// WriteHeaderNow is deferred to flush
+func (w *bufferedResponseWriter) WriteHeaderNow() {}
+
+// Write buffers body data
+func (w *bufferedResponseWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+// WriteString buffers body data
+func (w *bufferedResponseWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// Status returns the recorded status code, 200 if none was set
+func (w *bufferedResponseWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// Size returns the number of buffered body bytes
+func (w *bufferedResponseWriter) Size() int {
+	return w.body.Len()
+}
+
+// Written reports whether a status or body has been produced
+func (w *bufferedResponseWriter) Written() bool {
+	return w.status != 0 || w.body.Len() > 0
+}
+
+// Flush is deferred to flush
+func (w *bufferedResponseWriter) Flush() {}
+
+/**
+ * flush sends the buffered response to the wrapped writer
+ */
+func (w *bufferedResponseWriter) flush() {
+	if !w.Written() {
+		return
+	}
+	w.ResponseWriter.WriteHeader(w.Status())
+	if w.body.Len() == 0 {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+	_, _ = w.ResponseWriter.Write(w.body.Bytes())
+}
+
+/**
+ * discard drops the buffered response and the headers set by the handler
+ */
+func (w *bufferedResponseWriter) discard() {
+	w.status = 0
+	w.body.Reset()
+	header := w.ResponseWriter.Header()
+	for key := range header {
+		delete(header, key)
+	}
+	for key, values := range w.header {
+		header[key] = values
+	}
+}
@@ -0,0 +1,574 @@
+package internal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"gorm.io/gorm"
+
+	"github.com/zgsm-ai/client-manager/internal/testutil"
+)
+
+// txTestRow is the table written by the TxMiddleware tests
+type txTestRow struct {
+	ID uint
+}
+
+func TestTxMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	log := testutil.NewLogger()
+
+	tests := []struct {
+		name       string
+		handler    func(c *gin.Context, tx *gorm.DB)
+		wantStatus int
+		wantETag   string
+		committed  bool
+	}{
+		{
+			name:       "2xx commits",
+			handler:    func(c *gin.Context, tx *gorm.DB) { c.JSON(http.StatusCreated, gin.H{}) },
+			wantStatus: http.StatusCreated,
+			wantETag:   `"1"`,
+			committed:  true,
+		},
+		{
+			name:       "500 rolls back",
+			handler:    func(c *gin.Context, tx *gorm.DB) { c.JSON(http.StatusInternalServerError, gin.H{}) },
+			wantStatus: http.StatusInternalServerError,
+			wantETag:   `"1"`,
+		},
+		{
+			name: "handler error rolls back",
+			handler: func(c *gin.Context, tx *gorm.DB) {
+				_ = c.Error(io.ErrUnexpectedEOF)
+				c.JSON(http.StatusOK, gin.H{})
+			},
+			wantStatus: http.StatusOK,
+			wantETag:   `"1"`,
+		},
+		{
+			name: "failed commit answers 500",
+			handler: func(c *gin.Context, tx *gorm.DB) {
+				tx.Rollback()
+				c.JSON(http.StatusCreated, gin.H{})
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testutil.NewDB(t, nil)
+			if err := db.AutoMigrate(&txTestRow{}); err != nil {
+				t.Fatal(err)
+			}
+			hookRan, rollbackRan := false, false
+			r := gin.New()
+			r.POST("/", TxMiddleware(db, log), func(c *gin.Context) {
+				ctx := c.Request.Context()
+				tx := TxFromContext(ctx)
+				if err := tx.Create(&txTestRow{}).Error; err != nil {
+					t.Fatal(err)
+				}
+				AfterCommit(ctx, func() { hookRan = true })
+				AfterRollback(ctx, func() { rollbackRan = true })
+				c.Header("ETag", `"1"`)
+				tt.handler(c, tx)
+			})
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("ETag = %q, want %q", got, tt.wantETag)
+			}
+			var count int64
+			db.Model(&txTestRow{}).Count(&count)
+			if got := count == 1; got != tt.committed {
+				t.Errorf("row committed = %v, want %v", got, tt.committed)
+			}
+			if hookRan != tt.committed {
+				t.Errorf("after commit hook ran = %v, want %v", hookRan, tt.committed)
+			}
+			if rollbackRan == tt.committed {
+				t.Errorf("after rollback hook ran = %v, want %v", rollbackRan, !tt.committed)
+			}
+		})
+	}
+}
+
+func TestAfterCommitWithoutTransaction(t *testing.T) {
+	ctx := httptest.NewRequest(http.MethodGet, "/", nil).Context()
+	ran, rolledBack := false, false
+	AfterCommit(ctx, func() { ran = true })
+	AfterRollback(ctx, func() { rolledBack = true })
+	if !ran {
+		t.Errorf("AfterCommit without a request transaction did not run immediately")
+	}
+	if rolledBack {
+		t.Errorf("AfterRollback without a request transaction ran")
+	}
+}
+
+func TestMetricsAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	basic := func(username, password string) func(r *http.Request) {
+		return func(r *http.Request) { r.SetBasicAuth(username, password) }
+	}
+	bearer := func(token string) func(r *http.Request) {
+		return func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) }
+	}
+	anonymous := func(r *http.Request) {}
+
+	tests := []struct {
+		name      string
+		cfg       MetricsAuthConfig
+		authorize func(r *http.Request)
+		want      int
+	}{
+		{"no credentials configured", MetricsAuthConfig{}, anonymous, http.StatusOK},
+		{"basic auth", MetricsAuthConfig{Username: "prom", Password: "secret"}, basic("prom", "secret"), http.StatusOK},
+		{"wrong password", MetricsAuthConfig{Username: "prom", Password: "secret"}, basic("prom", "wrong"), http.StatusUnauthorized},
+		{"anonymous with basic auth", MetricsAuthConfig{Username: "prom", Password: "secret"}, anonymous, http.StatusUnauthorized},
+		{"password only", MetricsAuthConfig{Password: "secret"}, basic("", "secret"), http.StatusOK},
+		{"anonymous with password only", MetricsAuthConfig{Password: "secret"}, anonymous, http.StatusUnauthorized},
+		{"token", MetricsAuthConfig{Token: "t0k"}, bearer("t0k"), http.StatusOK},
+		{"wrong token", MetricsAuthConfig{Token: "t0k"}, bearer("other"), http.StatusUnauthorized},
+		{"token with basic auth configured", MetricsAuthConfig{Username: "prom", Password: "secret", Token: "t0k"}, bearer("t0k"), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/metrics", MetricsAuthMiddleware(tt.cfg), func(c *gin.Context) { c.Status(http.StatusOK) })
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			tt.authorize(req)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("401 without a WWW-Authenticate challenge")
+			}
+		})
+	}
+}
+
+func TestGzipMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	large := strings.Repeat("x", 2048)
+	alreadyGzipped := func(t *testing.T) []byte {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		if _, err := gz.Write([]byte(large)); err != nil {
+			t.Fatal(err)
+		}
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		handler        func(t *testing.T, c *gin.Context)
+		compressed     bool
+		want           string
+	}{
+		{
+			name:           "large json",
+			acceptEncoding: "gzip, deflate",
+			handler:        func(t *testing.T, c *gin.Context) { c.String(http.StatusOK, large) },
+			compressed:     true,
+			want:           large,
+		},
+		{
+			name:           "small json",
+			acceptEncoding: "gzip",
+			handler:        func(t *testing.T, c *gin.Context) { c.String(http.StatusOK, "small") },
+			want:           "small",
+		},
+		{
+			name:           "gzip not accepted",
+			acceptEncoding: "gzip;q=0",
+			handler:        func(t *testing.T, c *gin.Context) { c.String(http.StatusOK, large) },
+			want:           large,
+		},
+		{
+			name:           "content type not allowed",
+			acceptEncoding: "gzip",
+			handler:        func(t *testing.T, c *gin.Context) { c.Data(http.StatusOK, "application/octet-stream", []byte(large)) },
+			want:           large,
+		},
+		{
+			name:           "gzipped log download",
+			acceptEncoding: "gzip",
+			handler: func(t *testing.T, c *gin.Context) {
+				c.Header("Content-Encoding", "gzip")
+				c.Data(http.StatusOK, "text/plain", alreadyGzipped(t))
+			},
+			compressed: true,
+			want:       large,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(GzipMiddleware(1024, []string{"application/json", "text/plain"}))
+			r.GET("/", func(c *gin.Context) { tt.handler(t, c) })
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			body := w.Body.Bytes()
+			if got := w.Header().Get("Content-Encoding") == "gzip"; got != tt.compressed {
+				t.Fatalf("gzip encoded = %v, want %v", got, tt.compressed)
+			}
+			if tt.compressed {
+				// A single decompression must yield the original body
+				gz, err := gzip.NewReader(bytes.NewReader(body))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if body, err = io.ReadAll(gz); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if string(body) != tt.want {
+				t.Errorf("body = %.40q (%d bytes), want %.40q (%d bytes)", body, len(body), tt.want, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		want           bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"deflate, gzip;q=0.5", true},
+		{"gzip; q=0", false},
+		{"br", false},
+	}
+	for _, tt := range tests {
+		if got := acceptsGzip(tt.acceptEncoding); got != tt.want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", tt.acceptEncoding, got, tt.want)
+		}
+	}
+}
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(BodyLimitMiddleware(16, map[string]int64{http.MethodPost + " /upload": 64}))
+	readBody := func(c *gin.Context) {
+		if _, err := io.ReadAll(c.Request.Body); err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				RequestTooLarge(c, maxErr.Limit)
+				return
+			}
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Status(http.StatusOK)
+	}
+	r.POST("/batch", readBody)
+	r.POST("/upload", readBody)
+
+	tests := []struct {
+		name    string
+		path    string
+		size    int
+		chunked bool
+		want    int
+	}{
+		{"under the limit", "/batch", 16, false, http.StatusOK},
+		{"oversized batch", "/batch", 17, false, http.StatusRequestEntityTooLarge},
+		{"oversized chunked batch", "/batch", 17, true, http.StatusRequestEntityTooLarge},
+		{"upload under its override", "/upload", 64, false, http.StatusOK},
+		{"upload over its override", "/upload", 65, true, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(strings.Repeat("x", tt.size)))
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+			if w.Code == http.StatusRequestEntityTooLarge && !strings.Contains(w.Body.String(), `"code":"request.too_large"`) {
+				t.Errorf("body = %s, want the request.too_large envelope", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestDebugBodyMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name      string
+		level     logrus.Level
+		body      string
+		logged    string
+		truncated bool
+	}{
+		{"short body", logrus.DebugLevel, `{"a":1}`, `{"a":1}`, false},
+		{"long body", logrus.DebugLevel, strings.Repeat("x", 20), strings.Repeat("x", 8), true},
+		{"debug disabled", logrus.InfoLevel, `{"a":1}`, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, hook := logtest.NewNullLogger()
+			logger.SetLevel(tt.level)
+			var received string
+			r := gin.New()
+			r.Use(func(c *gin.Context) { c.Set("logger", logrus.NewEntry(logger)) })
+			r.Use(DebugBodyMiddleware(8))
+			r.POST("/", func(c *gin.Context) {
+				data, err := io.ReadAll(c.Request.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				received = string(data)
+				c.Status(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer secret")
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			if received != tt.body {
+				t.Errorf("handler read %q, want %q", received, tt.body)
+			}
+			if tt.logged == "" {
+				if len(hook.AllEntries()) != 0 {
+					t.Errorf("logged %d entries with debug disabled", len(hook.AllEntries()))
+				}
+				return
+			}
+			entry := hook.LastEntry()
+			if entry == nil {
+				t.Fatal("request body was not logged")
+			}
+			if entry.Data["body"] != tt.logged || entry.Data["truncated"] != tt.truncated {
+				t.Errorf("logged body %q truncated %v, want %q and %v", entry.Data["body"], entry.Data["truncated"], tt.logged, tt.truncated)
+			}
+			if headers := entry.Data["headers"].(map[string]string); headers["Authorization"] != "[REDACTED]" {
+				t.Errorf("Authorization logged as %q", headers["Authorization"])
+			}
+		})
+	}
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// wait returns a handler that answers after d unless the request is cancelled first
+	wait := func(d time.Duration) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			select {
+			case <-time.After(d):
+				c.Status(http.StatusOK)
+			case <-c.Request.Context().Done():
+			}
+		}
+	}
+
+	tests := []struct {
+		name      string
+		timeout   time.Duration
+		overrides map[string]time.Duration
+		handler   gin.HandlerFunc
+		want      int
+	}{
+		{"fast handler", 200 * time.Millisecond, nil, wait(0), http.StatusOK},
+		{"slow handler", 20 * time.Millisecond, nil, wait(time.Second), http.StatusGatewayTimeout},
+		{"route override", 20 * time.Millisecond, map[string]time.Duration{"GET /": time.Second}, wait(50 * time.Millisecond), http.StatusOK},
+		{"disabled", 0, nil, wait(50 * time.Millisecond), http.StatusOK},
+		{
+			name:    "response written before the deadline",
+			timeout: 20 * time.Millisecond,
+			handler: func(c *gin.Context) {
+				c.Status(http.StatusAccepted)
+				c.Writer.WriteHeaderNow()
+				<-c.Request.Context().Done()
+			},
+			want: http.StatusAccepted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(TimeoutMiddleware(tt.timeout, tt.overrides))
+			r.GET("/", tt.handler)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+			if w.Code == http.StatusGatewayTimeout && !strings.Contains(w.Body.String(), `"code":"timeout.error"`) {
+				t.Errorf("body = %s, want the timeout.error envelope", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestIsExemptPath(t *testing.T) {
+	prefixes := []string{"/healthz", " /swagger/ ", ""}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/healthz", true},
+		{"/healthz/deep", true},
+		{"/healthzx", false},
+		{"/swagger", true},
+		{"/swagger/index.html", true},
+		{"/", false},
+		{"/client-manager/api/v1/logs", false},
+	}
+	for _, tt := range tests {
+		if got := IsExemptPath(tt.path, prefixes); got != tt.want {
+			t.Errorf("IsExemptPath(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestAuthMiddlewareExemptPaths(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"id": "u1"}).SignedString([]byte("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.Use(AuthMiddleware([]string{"/healthz", "/swagger"}))
+	ok := func(c *gin.Context) { c.String(http.StatusOK, c.GetString("user_id")) }
+	r.GET("/healthz", ok)
+	r.GET("/swagger/*any", ok)
+	r.GET("/logs", ok)
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		want          int
+	}{
+		{"exempt path without token", "/healthz", "", http.StatusOK},
+		{"below an exempt prefix", "/swagger/index.html", "", http.StatusOK},
+		{"protected path without token", "/logs", "", http.StatusUnauthorized},
+		{"protected path with basic auth", "/logs", "Basic dTE6cHc=", http.StatusUnauthorized},
+		{"protected path with invalid token", "/logs", "Bearer nope", http.StatusUnauthorized},
+		{"protected path with token", "/logs", "Bearer " + token, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d, body %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCORSMiddlewareExemptPaths(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(CORSMiddleware([]string{"/metrics"}))
+	r.GET("/metrics", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/logs", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		path string
+		cors bool
+	}{
+		{"/metrics", false},
+		{"/logs", true},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if got := w.Header().Get("Access-Control-Allow-Origin") != ""; got != tt.cors {
+			t.Errorf("%s has CORS headers = %v, want %v", tt.path, got, tt.cors)
+		}
+	}
+}
+
+func TestConcurrencyLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ConcurrencyLimitMiddleware(1, 1500*time.Millisecond, []string{"/healthz"}))
+	entered, release := make(chan struct{}), make(chan struct{})
+	r.GET("/slow", func(c *gin.Context) {
+		close(entered)
+		<-release
+		c.Status(http.StatusOK)
+	})
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/healthz", ok)
+	r.GET("/logs", ok)
+
+	// Hold the only slot until the table has run
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		done <- w.Code
+	}()
+	<-entered
+
+	tests := []struct {
+		path       string
+		status     int
+		retryAfter string
+	}{
+		{"/logs", http.StatusServiceUnavailable, "2"},
+		{"/healthz", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.status {
+			t.Errorf("%s while saturated = %d, want %d", tt.path, w.Code, tt.status)
+		}
+		if got := w.Header().Get("Retry-After"); got != tt.retryAfter {
+			t.Errorf("%s Retry-After = %q, want %q", tt.path, got, tt.retryAfter)
+		}
+	}
+
+	close(release)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("held request = %d, want %d", code, http.StatusOK)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/logs", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("after release = %d, want %d", w.Code, http.StatusOK)
+	}
+}
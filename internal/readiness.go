@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+)
+
+/**
+ * CheckDatabase verifies the database answers a ping
+ * @returns {error} Error if the database is not initialized or unreachable
+ */
+func CheckDatabase() error {
+	if DB == nil {
+		return fmt.Errorf("database is not initialized")
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Ping()
+}
+
+/**
+ * CheckDirUsable verifies a path resolves to an existing directory
+ * @param {string} dir - Directory to check, symlinks are followed
+ * @returns {error} Descriptive error for missing paths, broken symlinks and non-directories
+ * @description
+ * - Only stats the path, cheap enough to run before every upload
+ */
+func CheckDirUsable(dir string) error {
+	info, err := os.Stat(dir)
+	if err == nil {
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", dir)
+		}
+		return nil
+	}
+	if os.IsNotExist(err) {
+		if _, lerr := os.Lstat(dir); lerr == nil {
+			return fmt.Errorf("%s is a broken symlink", dir)
+		}
+		return fmt.Errorf("%s does not exist", dir)
+	}
+	return fmt.Errorf("%s is not accessible: %w", dir, err)
+}
+
+/**
+ * CheckDirWritable verifies files can be created in a directory
+ * @param {string} dir - Directory to probe, created if missing
+ * @returns {error} Error if the directory cannot be created or written
+ * @description
+ * - Follows symlinks, a broken symlink or a file at dir is reported as such
+ * - Creates and removes a temporary probe file
+ * - Detects read-only volumes before uploads fail on them
+ */
+func CheckDirWritable(dir string) error {
+	if err := CheckDirUsable(dir); err != nil {
+		if _, lerr := os.Lstat(dir); !os.IsNotExist(lerr) {
+			return err
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+	}
+	f, err := os.CreateTemp(dir, ".ready-*")
+	if err != nil {
+		return fmt.Errorf("%s is not writable: %w", dir, err)
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckDirUsable(t *testing.T) {
+	base := t.TempDir()
+	dir := filepath.Join(base, "dir")
+	file := filepath.Join(base, "file")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{"dir-link": dir, "file-link": file, "broken-link": filepath.Join(base, "gone")}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(base, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr string
+	}{
+		{"directory", dir, ""},
+		{"symlink to a directory", filepath.Join(base, "dir-link"), ""},
+		{"file", file, "is not a directory"},
+		{"symlink to a file", filepath.Join(base, "file-link"), "is not a directory"},
+		{"missing path", filepath.Join(base, "missing"), "does not exist"},
+		{"broken symlink", filepath.Join(base, "broken-link"), "is a broken symlink"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckDirUsable(tt.path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("CheckDirUsable: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CheckDirUsable error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckDirWritable(t *testing.T) {
+	base := t.TempDir()
+	file := filepath.Join(base, "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(base, "gone"), filepath.Join(base, "broken-link")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{"directory", base, false},
+		{"missing directory is created", filepath.Join(base, "new", "logs"), false},
+		{"file", file, true},
+		{"broken symlink", filepath.Join(base, "broken-link"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckDirWritable(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckDirWritable error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			entries, err := os.ReadDir(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, entry := range entries {
+				if strings.HasPrefix(entry.Name(), ".ready-") {
+					t.Errorf("probe file %s was left behind", entry.Name())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+/**
+ * Scheduler manages background goroutines of the application
+ * @description
+ * - All jobs share a context that is cancelled by Stop
+ * - Stop waits for every job goroutine to return
+ * - Used for periodic jobs such as log retention
+ */
+type Scheduler struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	log    *logrus.Logger
+}
+
+/**
+ * NewScheduler creates a new Scheduler instance
+ * @param {*logrus.Logger} log - Logger instance
+ * @returns {*Scheduler} New Scheduler instance
+ */
+func NewScheduler(log *logrus.Logger) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		ctx:    ctx,
+		cancel: cancel,
+		log:    log,
+	}
+}
+
+/**
+ * Go runs a function in a managed goroutine
+ * @param {string} name - Job name used in logs
+ * @param {func(context.Context)} fn - Function to run, must return once ctx is cancelled
+ * @description
+ * - Useful for long-running loops such as reconnection attempts
+ * - The goroutine is tracked so Stop can wait for it
+ */
+func (s *Scheduler) Go(name string, fn func(ctx context.Context)) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.log.WithField("job", name).Debug("Background job started")
+		fn(s.ctx)
+		s.log.WithField("job", name).Debug("Background job stopped")
+	}()
+}
+
+/**
+ * Every runs a job periodically until the scheduler is stopped
+ * @param {string} name - Job name used in logs
+ * @param {time.Duration} interval - Time between two runs
+ * @param {func(context.Context)} job - Job to run, should honour ctx cancellation
+ * @description
+ * - The first run happens after one interval
+ * - A run in progress receives the cancelled context on shutdown
+ * - Non-positive intervals are ignored
+ */
+func (s *Scheduler) Every(name string, interval time.Duration, job func(ctx context.Context)) {
+	if interval <= 0 {
+		s.log.WithField("job", name).Warn("Ignoring job with non-positive interval")
+		return
+	}
+	s.Go(name, func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				job(ctx)
+			}
+		}
+	})
+}
+
+/**
+ * Stop cancels all jobs and waits for them to finish
+ * @description
+ * - Safe to call more than once
+ * - Blocks until every job goroutine has returned
+ */
+func (s *Scheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
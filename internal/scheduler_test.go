@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zgsm-ai/client-manager/internal/testutil"
+)
+
+// newTestScheduler returns a scheduler that is stopped when the test ends
+func newTestScheduler(t *testing.T) *Scheduler {
+	log := testutil.NewLogger()
+	s := NewScheduler(log)
+	t.Cleanup(s.Stop)
+	return s
+}
+
+func TestSchedulerStopWaitsForJobs(t *testing.T) {
+	tests := []struct {
+		name  string
+		start func(s *Scheduler, job func(ctx context.Context))
+	}{
+		{"Go", func(s *Scheduler, job func(ctx context.Context)) { s.Go("job", job) }},
+		{"Every", func(s *Scheduler, job func(ctx context.Context)) { s.Every("job", time.Millisecond, job) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestScheduler(t)
+			started := make(chan struct{}, 1)
+			var exited atomic.Bool
+			tt.start(s, func(ctx context.Context) {
+				select {
+				case started <- struct{}{}:
+				default:
+				}
+				<-ctx.Done()
+				// A job cut off mid-delete would be observed here
+				time.Sleep(10 * time.Millisecond)
+				exited.Store(true)
+			})
+			select {
+			case <-started:
+			case <-time.After(time.Second):
+				t.Fatal("job did not start")
+			}
+
+			s.Stop()
+			if !exited.Load() {
+				t.Errorf("Stop returned before the job exited")
+			}
+			s.Stop()
+		})
+	}
+}
+
+func TestSchedulerEveryStopsRunning(t *testing.T) {
+	s := newTestScheduler(t)
+	var runs atomic.Int32
+	s.Every("job", time.Millisecond, func(ctx context.Context) { runs.Add(1) })
+	time.Sleep(20 * time.Millisecond)
+
+	s.Stop()
+	stopped := runs.Load()
+	time.Sleep(20 * time.Millisecond)
+	if stopped == 0 {
+		t.Errorf("job never ran")
+	}
+	if got := runs.Load(); got != stopped {
+		t.Errorf("job ran %d times after Stop", got-stopped)
+	}
+}
+
+func TestSchedulerEveryIgnoresNonPositiveInterval(t *testing.T) {
+	s := newTestScheduler(t)
+	var runs atomic.Int32
+	s.Every("job", 0, func(ctx context.Context) { runs.Add(1) })
+	s.Stop()
+	if got := runs.Load(); got != 0 {
+		t.Errorf("job with zero interval ran %d times", got)
+	}
+}
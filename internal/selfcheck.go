@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+/**
+ * SelfCheck verifies the application is ready to serve and logs a summary
+ * @param {*gorm.DB} db - Database connection
+ * @param {*logrus.Logger} log - Application logger
+ * @returns {error} Error for fatal issues the service cannot run with
+ * @description
+ * - Pings the database
+ * - Verifies the tables of all migrated models exist
+ * - Verifies the storage base directory is a writable directory, creating it if missing
+ * - Reports the configuration source and optional features
+ * - Logs a single summary line, at warn level if anything is degraded
+ * @throws
+ * - Database ping errors
+ * - Missing table errors
+ * - Unusable storage base directory errors
+ */
+func SelfCheck(db *gorm.DB, log *logrus.Logger) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get underlying database: %w", err)
+	}
+	if err := sqlDB.Ping(); err != nil {
+		return fmt.Errorf("database is not reachable: %w", err)
+	}
+
+	var missing []string
+	for _, model := range migratedModels {
+		if !db.Migrator().HasTable(model) {
+			stmt := &gorm.Statement{DB: db}
+			if err := stmt.Parse(model); err == nil {
+				missing = append(missing, stmt.Schema.Table)
+			} else {
+				missing = append(missing, fmt.Sprintf("%T", model))
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing database tables: %s", strings.Join(missing, ", "))
+	}
+
+	storageDir := GetStorageConfig().BaseDir
+	if err := CheckDirWritable(storageDir); err != nil {
+		return fmt.Errorf("storage base directory is unusable: %w", err)
+	}
+
+	configSource := viper.ConfigFileUsed()
+	if configSource == "" {
+		configSource = "defaults"
+	}
+	enabled := func(on bool) string {
+		if on {
+			return "enabled"
+		}
+		return "disabled"
+	}
+
+	fields := logrus.Fields{
+		"database":      "ok",
+		"tables":        len(migratedModels),
+		"storage":       storageDir,
+		"config_source": configSource,
+		"listen":        GetListenAddr(),
+		"jwks":          enabled(GetJWKSURL() != ""),
+		"admin_api":     enabled(GetAdminAPIKey() != ""),
+		"metrics_auth":  enabled(GetMetricsAuthConfig().Enabled()),
+	}
+	if GetJWKSURL() == "" {
+		log.WithFields(fields).Warn("Self-check passed, token signatures are not verified")
+		return nil
+	}
+	log.WithFields(fields).Info("Self-check passed")
+	return nil
+}
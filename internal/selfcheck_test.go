@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"gorm.io/gorm"
+
+	"github.com/zgsm-ai/client-manager/internal/testutil"
+	"github.com/zgsm-ai/client-manager/models"
+)
+
+func TestSelfCheck(t *testing.T) {
+	migrated := func(t *testing.T) *gorm.DB {
+		db := testutil.NewDB(t, nil)
+		if err := db.AutoMigrate(migratedModels...); err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}
+	tests := []struct {
+		name    string
+		db      func(t *testing.T) *gorm.DB
+		storage func(t *testing.T) string
+		wantErr string
+	}{
+		{
+			name:    "healthy",
+			db:      migrated,
+			storage: func(t *testing.T) string { return t.TempDir() },
+		},
+		{
+			name:    "missing storage directory is created",
+			db:      migrated,
+			storage: func(t *testing.T) string { return filepath.Join(t.TempDir(), "logs") },
+		},
+		{
+			name: "missing table",
+			db: func(t *testing.T) *gorm.DB {
+				db := testutil.NewDB(t, nil)
+				if err := db.AutoMigrate(&models.Log{}); err != nil {
+					t.Fatal(err)
+				}
+				return db
+			},
+			storage: func(t *testing.T) string { return t.TempDir() },
+			wantErr: "missing database tables: audit_entries",
+		},
+		{
+			name: "database unreachable",
+			db: func(t *testing.T) *gorm.DB {
+				db := migrated(t)
+				sqlDB, err := db.DB()
+				if err != nil {
+					t.Fatal(err)
+				}
+				sqlDB.Close()
+				return db
+			},
+			storage: func(t *testing.T) string { return t.TempDir() },
+			wantErr: "database is not reachable",
+		},
+		{
+			name: "storage is a file",
+			db:   migrated,
+			storage: func(t *testing.T) string {
+				path := filepath.Join(t.TempDir(), "file")
+				if err := os.WriteFile(path, nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return path
+			},
+			wantErr: "storage base directory is unusable",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.SetConfig(t, "storage.base_dir", tt.storage(t))
+			log, hook := logtest.NewNullLogger()
+
+			err := SelfCheck(tt.db(t), log)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("SelfCheck error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SelfCheck: %v", err)
+			}
+			entry := hook.LastEntry()
+			if entry == nil || entry.Data["database"] != "ok" || entry.Data["tables"] != len(migratedModels) {
+				t.Errorf("summary = %v, want database ok and %d tables", entry, len(migratedModels))
+			}
+			if entry != nil && entry.Level != logrus.WarnLevel {
+				t.Errorf("summary level = %s, want warn without a JWKS URL", entry.Level)
+			}
+		})
+	}
+}
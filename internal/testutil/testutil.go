@@ -0,0 +1,63 @@
+// Package testutil holds the fixtures shared by the package tests
+// It must not import internal, whose own tests use it
+package testutil
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// SetConfig overrides a configuration key for the duration of a test
+func SetConfig(tb testing.TB, key string, value interface{}) {
+	tb.Helper()
+	prev := viper.Get(key)
+	viper.Set(key, value)
+	tb.Cleanup(func() { viper.Set(key, prev) })
+}
+
+// NewLogger returns a logger discarding its output
+func NewLogger() *logrus.Logger {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return log
+}
+
+// NewDB opens a SQLite database in a temporary directory and migrates the given models
+// config may be nil, unset Logger and NowFunc default to silent and UTC like internal.Now
+func NewDB(tb testing.TB, config *gorm.Config, models ...interface{}) *gorm.DB {
+	tb.Helper()
+	if config == nil {
+		config = &gorm.Config{}
+	}
+	if config.Logger == nil {
+		config.Logger = logger.Default.LogMode(logger.Silent)
+	}
+	if config.NowFunc == nil {
+		config.NowFunc = func() time.Time { return time.Now().UTC() }
+	}
+
+	dsn := filepath.Join(tb.TempDir(), "test.db") + "?_pragma=busy_timeout(5000)"
+	db, err := gorm.Open(sqlite.Open(dsn), config)
+	if err != nil {
+		tb.Fatalf("open database: %v", err)
+	}
+	tb.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if len(models) > 0 {
+		if err := db.AutoMigrate(models...); err != nil {
+			tb.Fatalf("migrate database: %v", err)
+		}
+	}
+	return db
+}
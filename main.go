@@ -67,12 +67,14 @@ var rootCmd = &cobra.Command{
 
 		// Initialize controllers
 		logController := controllers.NewLogController(app.Logger, app.LogService)
+		auditController := controllers.NewAuditController(app.Logger, app.AuditService)
+		searchController := controllers.NewSearchController(app.Logger, app.SearchService)
 
 		// Create Gin engine
 		r := gin.Default()
 
 		// Setup all routes
-		router.SetupRoutes(r, logController, app.Logger)
+		router.SetupRoutes(r, logController, auditController, searchController, app.Logger)
 
 		// Start server
 		if err := services.StartServer(r, app.Logger); err != nil {
@@ -91,13 +93,18 @@ func init() {
 * Setup graceful shutdown handlers
 * @param {*services.AppContext} app - Application context containing database connection
 * @description
-* - Sets up signal handlers for SIGINT and SIGTERM
+* - Called once StartServer has returned after SIGINT or SIGTERM
+* - Stops background jobs and waits for them to finish
 * - Closes database connection gracefully
 * - Logs shutdown process
  */
 func gracefulShutdown(app *services.AppContext) {
 	app.Logger.Info("Shutting down application...")
 
+	// Stop background jobs before the database goes away
+	app.Scheduler.Stop()
+	app.Logger.Info("Background jobs stopped")
+
 	// Close database connection
 	if err := internal.CloseDB(); err != nil {
 		app.Logger.WithError(err).Error("Failed to close database connection")
@@ -10,14 +10,20 @@ import (
  * - Stores log data from clients
  * - Includes client and user identification
  * - Supports structured logging with module information
+ * - A client has at most one record per file name
  */
 type Log struct {
 	ID          uint      `json:"id" gorm:"primaryKey"`
-	ClientID    string    `json:"client_id" gorm:"index;not null"`
+	ClientID    string    `json:"client_id" gorm:"index;uniqueIndex:idx_logs_client_file;not null"`
 	UserID      string    `json:"user_id" gorm:"index"`
-	FileName    string    `json:"file_name" gorm:"index;not null"`
+	FileName    string    `json:"file_name" gorm:"index;uniqueIndex:idx_logs_client_file;not null"`
+	ModuleName  string    `json:"module_name" gorm:"index"`
 	FirstLineNo int64     `json:"first_line_no"`
 	LastLineNo  int64     `json:"end_line_no"`
+	SizeBytes   int64     `json:"size_bytes"`
+	ContentHash string    `json:"content_hash" gorm:"index"`
+	LineCount   int64     `json:"line_count"`
+	Compressed  bool      `json:"compressed"`
 	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 }
@@ -29,3 +35,75 @@ type Log struct {
 func (Log) TableName() string {
 	return "logs"
 }
+
+/**
+ * ClientLogSummary aggregates the logs uploaded by a client
+ * @description
+ * - Not a table, computed from the logs table
+ * - FirstUploadAt/LastUploadAt are null when the client has no logs
+ */
+type ClientLogSummary struct {
+	ClientID      string     `json:"client_id"`
+	TotalFiles    int64      `json:"total_files"`
+	TotalBytes    int64      `json:"total_bytes"`
+	FirstUploadAt *time.Time `json:"first_upload_at"`
+	LastUploadAt  *time.Time `json:"last_upload_at"`
+}
+
+/**
+ * DailyLogCount is the number of logs updated on one day
+ * @description
+ * - Not a table, computed from the logs table
+ * - Date is formatted as YYYY-MM-DD
+ */
+type DailyLogCount struct {
+	Date  string `json:"date"`
+	Count int64  `json:"count"`
+}
+
+/**
+ * ClientLogCount is the number of logs and stored bytes of one client
+ * @description
+ * - Not a table, computed from the logs table
+ */
+type ClientLogCount struct {
+	ClientID string `json:"client_id"`
+	Files    int64  `json:"files"`
+	Bytes    int64  `json:"bytes"`
+}
+
+/**
+ * ModuleLogCount is the number of logs of one module
+ * @description
+ * - Not a table, computed from the logs table
+ */
+type ModuleLogCount struct {
+	ModuleName string `json:"module_name"`
+	Count      int64  `json:"count"`
+}
+
+/**
+ * AuditEntry model records a single write operation
+ * @description
+ * - Append-only trail of create/update/delete operations
+ * - Before/After hold JSON summaries of the affected resource
+ * - Written in the same transaction as the audited operation
+ */
+type AuditEntry struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	Actor      string    `json:"actor" gorm:"index;not null"`
+	Action     string    `json:"action" gorm:"not null"`
+	Resource   string    `json:"resource" gorm:"index;not null"`
+	ResourceID string    `json:"resource_id" gorm:"index"`
+	Before     string    `json:"before" gorm:"type:text"`
+	After      string    `json:"after" gorm:"type:text"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index;autoCreateTime"`
+}
+
+/**
+ * TableName returns the table name for AuditEntry model
+ * @returns {string} Database table name
+ */
+func (AuditEntry) TableName() string {
+	return "audit_entries"
+}
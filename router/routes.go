@@ -1,6 +1,9 @@
 package router
 
 import (
+	"net/http"
+	"time"
+
 	"github.com/zgsm-ai/client-manager/controllers"
 	_ "github.com/zgsm-ai/client-manager/docs"
 	"github.com/zgsm-ai/client-manager/internal"
@@ -17,19 +20,29 @@ import (
  * Setup all routes for the application
  * @param {*gin.Engine} r - Gin engine
  * @param {*controllers.LogController} logController - Log controller
+ * @param {*controllers.AuditController} auditController - Audit controller
+ * @param {*controllers.SearchController} searchController - Search controller
  * @param {*logrus.Logger} logger - Application logger
  * @description
- * - Adds CORS middleware
+ * - Adds CORS middleware, skipping cors.exempt_paths
+ * - Adds bearer token authentication when auth.required is set, skipping auth.exempt_paths
+ *   and the Swagger UI
  * - Adds Prometheus middleware
  * - Adds request ID middleware
+ * - Limits concurrent requests when server.max_concurrent is set, health checks excluded
+ * - Adds request body size limit middleware
+ * - Adds request timeout middleware
+ * - Adds request body debug logging when log.debug_bodies is set
+ * - Adds gzip response compression middleware when enabled
  * - Sets up health check endpoints
  * - Sets up metrics endpoint
- * - Sets up Swagger documentation endpoint
+ * - Sets up Swagger documentation endpoint at swagger.path when swagger.enabled is set
  * - Sets up API routes
+ * - Answers unknown routes with 404 and wrong methods with 405 in the JSON error format
  */
-func SetupRoutes(r *gin.Engine, logController *controllers.LogController, logger *logrus.Logger) {
+func SetupRoutes(r *gin.Engine, logController *controllers.LogController, auditController *controllers.AuditController, searchController *controllers.SearchController, logger *logrus.Logger) {
 	// Add CORS middleware
-	r.Use(internal.CORSMiddleware())
+	r.Use(internal.CORSMiddleware(internal.GetCORSExemptPaths()))
 
 	// Add Prometheus middleware
 	r.Use(internal.PrometheusMiddleware())
@@ -37,17 +50,59 @@ func SetupRoutes(r *gin.Engine, logController *controllers.LogController, logger
 	// Add request ID middleware
 	r.Use(internal.RequestIDMiddleware())
 
+	// Add global concurrency limit, health checks stay responsive when saturated
+	if max := internal.GetMaxConcurrent(); max > 0 {
+		r.Use(internal.ConcurrencyLimitMiddleware(max, internal.GetRetryAfter(), internal.GetConcurrencyExemptPaths()))
+	}
+
+	// Add authentication middleware, public paths are declared in auth.exempt_paths
+	swagger := internal.GetSwaggerConfig()
+	if internal.GetAuthRequired() {
+		exempt := internal.GetAuthExemptPaths()
+		if swagger.Enabled {
+			exempt = append(exempt, swagger.Path)
+		}
+		r.Use(internal.AuthMiddleware(exempt))
+	}
+
+	// Add request body size limit middleware, uploads get their own limit
+	r.Use(internal.BodyLimitMiddleware(internal.GetMaxBodyBytes(), map[string]int64{
+		http.MethodPost + " /client-manager/api/v1/logs": internal.GetUploadMaxBodyBytes(),
+	}))
+
+	// Add request timeout middleware, uploads get their own timeout
+	r.Use(internal.TimeoutMiddleware(internal.GetRequestTimeout(), map[string]time.Duration{
+		http.MethodPost + " /client-manager/api/v1/logs": internal.GetUploadRequestTimeout(),
+	}))
+
+	// Add request body debug logging
+	if enabled, maxBytes := internal.GetDebugBodies(); enabled {
+		r.Use(internal.DebugBodyMiddleware(maxBytes))
+	}
+
+	// Add response compression middleware
+	if cfg := internal.GetCompressionConfig(); cfg.Enabled {
+		r.Use(internal.GzipMiddleware(cfg.MinSize, cfg.ContentTypes))
+	}
+
 	// Health check endpoints
 	setupHealthCheckRoutes(r, logger)
 
-	// Metrics endpoint
-	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	// Metrics endpoint, protected when metrics.auth is configured
+	r.GET("/metrics", internal.MetricsAuthMiddleware(internal.GetMetricsAuthConfig()), gin.WrapH(promhttp.Handler()))
 
-	// Swagger documentation
-	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	// Swagger documentation, the UI loads the spec from doc.json next to it
+	if swagger.Enabled {
+		r.GET(swagger.Path+"/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	}
 
 	// Setup API routes
-	setupAPIRoutes(r, logController)
+	setupAPIRoutes(r, logController, auditController, searchController, logger)
+
+	// Unknown routes and methods
+	r.HandleMethodNotAllowed = true
+	r.NoRoute(internal.RouteNotFound)
+	r.NoMethod(internal.MethodNotAllowed)
 }
 
 // setupHealthCheckRoutes configures health check routes
@@ -59,6 +114,8 @@ func SetupRoutes(r *gin.Engine, logController *controllers.LogController, logger
  * - Sets up /healthz endpoint
  * - Sets up /live endpoint
  * - Sets up /ready endpoint
+ * - Sets up /stats/runtime endpoint
+ * - Sets up admin-only /admin/stats endpoint
  */
 func setupHealthCheckRoutes(r *gin.Engine, logger *logrus.Logger) {
 	healthController := controllers.NewHealthController(logger)
@@ -66,6 +123,8 @@ func setupHealthCheckRoutes(r *gin.Engine, logger *logrus.Logger) {
 	r.GET("/healthz", healthController.GetHealth)
 	r.GET("/live", healthController.LiveHandler)
 	r.GET("/ready", healthController.ReadyHandler)
+	r.GET("/stats/runtime", healthController.GetRuntimeStats)
+	r.GET("/admin/stats", internal.AdminAuthMiddleware(internal.GetAdminAPIKey()), healthController.GetAdminStats)
 }
 
 // setupAPIRoutes configures API routes for the application
@@ -73,21 +132,52 @@ func setupHealthCheckRoutes(r *gin.Engine, logger *logrus.Logger) {
  * Setup API routes for the application
  * @param {*gin.Engine} r - Gin engine
  * @param {*controllers.LogController} logController - Log controller
+ * @param {*controllers.AuditController} auditController - Audit controller
+ * @param {*controllers.SearchController} searchController - Search controller
+ * @param {*logrus.Logger} logger - Application logger
  * @description
  * - Sets up configuration API routes
  * - Sets up feedback API routes
  * - Sets up log API routes
+ * - Sets up client API routes
+ * - Limits concurrent log uploads when uploads.max_concurrent is set
+ * - Runs log uploads in one transaction when database.request_transactions is set
+ * - Sets up admin-only audit routes
+ * - Sets up admin-only search route
  */
-func setupAPIRoutes(r *gin.Engine, logController *controllers.LogController) {
+func setupAPIRoutes(r *gin.Engine, logController *controllers.LogController, auditController *controllers.AuditController, searchController *controllers.SearchController, logger *logrus.Logger) {
+	uploadHandlers := []gin.HandlerFunc{}
+	if max := internal.GetUploadMaxConcurrent(); max > 0 {
+		uploadHandlers = append(uploadHandlers, internal.ConcurrencyLimitMiddleware(max, internal.GetUploadRetryAfter(), nil))
+	}
+	if internal.GetDBRequestTransactions() {
+		uploadHandlers = append(uploadHandlers, internal.TxMiddleware(internal.GetDB(), logger))
+	}
+	uploadHandlers = append(uploadHandlers, logController.PostLog)
+
 	// Setup API routes
 	api := r.Group("/client-manager/api/v1")
 	{
 		// Log routes
 		logs := api.Group("/logs")
 		{
-			logs.POST("", logController.PostLog)
+			logs.POST("", uploadHandlers...)
 			logs.GET("", logController.ListLogs)
 			logs.GET("/:client_id/:file_name", logController.GetLogs)
+			logs.GET("/stats", logController.GetLogStats)
+			logs.GET("/modules", logController.ListModules)
 		}
+
+		// Client routes, kept out of /logs where they would shadow GET /:client_id/:file_name
+		clients := api.Group("/clients")
+		{
+			clients.GET("/:client_id/logs/summary", logController.GetClientSummary)
+		}
+
+		// Audit routes
+		api.GET("/audit", internal.AdminAuthMiddleware(internal.GetAdminAPIKey()), auditController.ListAuditEntries)
+
+		// Search routes
+		api.GET("/search", internal.AdminAuthMiddleware(internal.GetAdminAPIKey()), searchController.Search)
 	}
 }
@@ -0,0 +1,839 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+
+	"github.com/zgsm-ai/client-manager/controllers"
+	"github.com/zgsm-ai/client-manager/dao"
+	"github.com/zgsm-ai/client-manager/internal/testutil"
+	"github.com/zgsm-ai/client-manager/models"
+	"github.com/zgsm-ai/client-manager/services"
+)
+
+// newTestRouter serves the routes over a temporary database and storage directory
+func newTestRouter(t *testing.T) (*gin.Engine, *gorm.DB, string) {
+	t.Helper()
+	baseDir := t.TempDir()
+	testutil.SetConfig(t, "storage.base_dir", baseDir)
+
+	db := testutil.NewDB(t, nil, &models.Log{}, &models.AuditEntry{})
+	log := testutil.NewLogger()
+	logDAO, auditDAO := dao.NewLogDAO(db, log), dao.NewAuditDAO(db, log)
+	auditService := services.NewAuditService(auditDAO, log)
+	logService := services.NewLogService(logDAO, auditService, log)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	SetupRoutes(r,
+		controllers.NewLogController(log, logService),
+		controllers.NewAuditController(log, auditService),
+		controllers.NewSearchController(log, services.NewSearchService(logDAO, auditDAO, log)),
+		log)
+	return r, db, baseDir
+}
+
+func TestLogRoutesForClientNamedClient(t *testing.T) {
+	r, db, baseDir := newTestRouter(t)
+	if err := db.Create(&models.Log{ClientID: "client", FileName: "foo.log", SizeBytes: 5}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(baseDir, "client"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(baseDir, "client", "foo.log"), []byte("line\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/client-manager/api/v1/logs/client/foo.log", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "line\n" {
+		t.Errorf("download = %d %q, want 200 with the file content", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/client-manager/api/v1/clients/client/logs/summary", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("summary status = %d, want 200, body %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data models.ClientLogSummary `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data.ClientID != "client" || resp.Data.TotalFiles != 1 || resp.Data.TotalBytes != 5 {
+		t.Errorf("summary = %+v, want client with 1 file of 5 bytes", resp.Data)
+	}
+}
+
+// counterValue returns the value of a registered counter, 0 if the labels were never used
+func counterValue(t *testing.T, name string, labels map[string]string) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			matched := 0
+			for _, label := range metric.GetLabel() {
+				if value, ok := labels[label.GetName()]; ok && value == label.GetValue() {
+					matched++
+				}
+			}
+			if matched == len(labels) {
+				return metric.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+// logsRetrieved returns logs_received_total for retrievals of a client
+func logsRetrieved(t *testing.T, clientID string) float64 {
+	return counterValue(t, "logs_received_total", map[string]string{"client_id": clientID, "module": "retrieve"})
+}
+
+func TestGetLogsRecordsNormalizedClientID(t *testing.T) {
+	r, _, baseDir := newTestRouter(t)
+	testutil.SetConfig(t, "client_id.lowercase", true)
+	if err := os.MkdirAll(filepath.Join(baseDir, "client-a"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(baseDir, "client-a", "a.log"), []byte("line\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		clientID string
+		status   int
+		label    string
+		recorded float64
+	}{
+		{"normalized", "%20Client-A%20", http.StatusOK, "client-a", 1},
+		{"rejected", "bad$id", http.StatusBadRequest, "bad$id", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := logsRetrieved(t, tt.label)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/client-manager/api/v1/logs/"+tt.clientID+"/a.log", nil))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+			if got := logsRetrieved(t, tt.label) - before; got != tt.recorded {
+				t.Errorf("retrievals recorded for %q = %v, want %v", tt.label, got, tt.recorded)
+			}
+		})
+	}
+}
+
+// testToken signs a token carrying the given claims, its signature is not verified without a JWKS URL
+func testToken(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// uploadRequest builds a log upload request of user u1
+func uploadRequest(t *testing.T, fileName, content, ifMatch string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("logfile", fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := part.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	args, err := json.Marshal(services.UploadLogArgs{ClientID: "c1", UserID: "u1", FileName: fileName, LastLineNo: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := form.WriteField("args", string(args)); err != nil {
+		t.Fatal(err)
+	}
+	if err := form.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/client-manager/api/v1/logs", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+testToken(t, jwt.MapClaims{"id": "u1"}))
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	return req
+}
+
+// postLog uploads a log file as user u1
+func postLog(t *testing.T, r *gin.Engine, fileName, content, ifMatch string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, uploadRequest(t, fileName, content, ifMatch))
+	return w
+}
+
+func TestPostLogCountsRejections(t *testing.T) {
+	tests := []struct {
+		name   string
+		setup  func(t *testing.T, r *gin.Engine, baseDir string)
+		status int
+		reason string
+	}{
+		{
+			name: "storage unavailable",
+			setup: func(t *testing.T, r *gin.Engine, baseDir string) {
+				if err := os.RemoveAll(baseDir); err != nil {
+					t.Fatal(err)
+				}
+			},
+			status: http.StatusServiceUnavailable,
+			reason: "storage_unavailable",
+		},
+		{
+			name: "precondition failed",
+			setup: func(t *testing.T, r *gin.Engine, baseDir string) {
+				if w := postLog(t, r, "a.log", "first\n", ""); w.Code != http.StatusCreated {
+					t.Fatalf("first upload status = %d, body %s", w.Code, w.Body.String())
+				}
+			},
+			status: http.StatusPreconditionFailed,
+			reason: "precondition_failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _, baseDir := newTestRouter(t)
+			tt.setup(t, r, baseDir)
+			before := counterValue(t, "log_uploads_rejected_total", map[string]string{"reason": tt.reason})
+
+			w := postLog(t, r, "a.log", "second\n", `"99"`)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+			after := counterValue(t, "log_uploads_rejected_total", map[string]string{"reason": tt.reason})
+			if after-before != 1 {
+				t.Errorf("log_uploads_rejected_total{reason=%q} grew by %v, want 1", tt.reason, after-before)
+			}
+		})
+	}
+}
+
+func TestGetLogStatsFormats(t *testing.T) {
+	r, db, _ := newTestRouter(t)
+	if err := db.Create(&models.Log{ClientID: "c1", FileName: "a.log"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	var stored models.Log
+	if err := db.First(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	today := stored.UpdatedAt.UTC().Format("2006-01-02")
+
+	tests := []struct {
+		name        string
+		query       string
+		accept      string
+		contentType string
+		want        string
+	}{
+		{"json by default", "", "", "application/json", `"total_logs":1`},
+		{"csv by format", "?format=csv", "", "text/csv", "date,count\n" + today + ",1\n"},
+		{"csv by accept", "", "text/csv", "text/csv", "date,count\n" + today + ",1\n"},
+		{"format overrides accept", "?format=json", "text/csv", "application/json", `"total_logs":1`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/client-manager/api/v1/logs/stats"+tt.query, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200, body %s", w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, tt.contentType) {
+				t.Errorf("Content-Type = %q, want %s", got, tt.contentType)
+			}
+			if !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("body = %q, want it to contain %q", w.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestPostLogReportsCreated(t *testing.T) {
+	r, _, _ := newTestRouter(t)
+	tests := []struct {
+		name    string
+		content string
+		status  int
+		created bool
+	}{
+		{"new file", "first\n", http.StatusCreated, true},
+		{"re-upload", "second\n", http.StatusOK, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postLog(t, r, "a.log", tt.content, "")
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+			var resp struct {
+				Created bool `json:"created"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Created != tt.created {
+				t.Errorf("created = %v, want %v", resp.Created, tt.created)
+			}
+		})
+	}
+}
+
+func TestPostLogIfMatchForms(t *testing.T) {
+	r, _, _ := newTestRouter(t)
+	// The steps run in order against the same log
+	tests := []struct {
+		name    string
+		ifMatch string
+		status  int
+	}{
+		{"any before the log exists", "*", http.StatusPreconditionFailed},
+		{"unconditional upload", "", http.StatusCreated},
+		{"any once the log exists", "*", http.StatusOK},
+		{"weak tag", `W/"1"`, http.StatusPreconditionFailed},
+		{"strong tag", `"1"`, http.StatusOK},
+		{"malformed tag", "1", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postLog(t, r, "a.log", "line\n", tt.ifMatch)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestPostLogConcurrencyLimit(t *testing.T) {
+	testutil.SetConfig(t, "uploads.max_concurrent", 1)
+	r, _, _ := newTestRouter(t)
+
+	// The first upload holds the only slot while its body is still arriving
+	slow := uploadRequest(t, "slow.log", "slow\n", "")
+	body, err := io.ReadAll(slow.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr, pw := io.Pipe()
+	slow.Body = pr
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, slow)
+		done <- w
+	}()
+	if _, err := pw.Write(body[:len(body)/2]); err != nil {
+		t.Fatal(err)
+	}
+
+	w := postLog(t, r, "fast.log", "fast\n", "")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("upload while saturated = %d, want 503, body %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want 1", got)
+	}
+
+	if _, err := pw.Write(body[len(body)/2:]); err != nil {
+		t.Fatal(err)
+	}
+	pw.Close()
+	if w := <-done; w.Code != http.StatusCreated {
+		t.Fatalf("slow upload = %d, want 201, body %s", w.Code, w.Body.String())
+	}
+	if w := postLog(t, r, "fast.log", "fast\n", ""); w.Code != http.StatusCreated {
+		t.Errorf("upload after the slot was released = %d, want 201, body %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAdminRoutesRequireAdminKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		adminKey string
+		header   string
+		status   int
+	}{
+		{"admin endpoints disabled", "/client-manager/api/v1/audit?resource=log", "", "", http.StatusForbidden},
+		{"missing key", "/client-manager/api/v1/audit?resource=log", "s3cret", "", http.StatusUnauthorized},
+		{"wrong key", "/client-manager/api/v1/audit?resource=log", "s3cret", "other", http.StatusUnauthorized},
+		{"admin key", "/client-manager/api/v1/audit?resource=log", "s3cret", "s3cret", http.StatusOK},
+		{"stats disabled", "/admin/stats", "", "", http.StatusForbidden},
+		{"stats without key", "/admin/stats", "s3cret", "", http.StatusUnauthorized},
+		{"stats with admin key", "/admin/stats", "s3cret", "s3cret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.SetConfig(t, "admin.api_key", tt.adminKey)
+			r, _, _ := newTestRouter(t)
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("X-Admin-Key", tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestPostLogBodyLimits(t *testing.T) {
+	content := strings.Repeat("line\n", 100)
+	tests := []struct {
+		name        string
+		uploadLimit int64
+		status      int
+	}{
+		{"upload under its own limit", 1 << 20, http.StatusCreated},
+		{"upload over its own limit", 256, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The global limit is below the upload size, only the upload override applies
+			testutil.SetConfig(t, "server.max_body_bytes", 64)
+			testutil.SetConfig(t, "uploads.max_body_bytes", tt.uploadLimit)
+			r, _, _ := newTestRouter(t)
+
+			w := postLog(t, r, "a.log", content, "")
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.status == http.StatusRequestEntityTooLarge && !strings.Contains(w.Body.String(), `"code":"request.too_large"`) {
+				t.Errorf("body = %s, want the request.too_large envelope", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestListLogsSparseFieldsets(t *testing.T) {
+	r, db, _ := newTestRouter(t)
+	if err := db.Create(&models.Log{ClientID: "c1", UserID: "u1", FileName: "a.log", SizeBytes: 5}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		keys   []string
+	}{
+		{"subset", "?fields=client_id,file_name", http.StatusOK, []string{"client_id", "file_name"}},
+		{"unknown field", "?fields=client_id,secret", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/client-manager/api/v1/logs"+tt.query, nil))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.keys == nil {
+				return
+			}
+			var resp struct {
+				Data []map[string]interface{} `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Data) != 1 {
+				t.Fatalf("got %d logs, want 1", len(resp.Data))
+			}
+			if len(resp.Data[0]) != len(tt.keys) {
+				t.Errorf("log = %v, want only %v", resp.Data[0], tt.keys)
+			}
+			for _, key := range tt.keys {
+				if _, ok := resp.Data[0][key]; !ok {
+					t.Errorf("log = %v, missing %s", resp.Data[0], key)
+				}
+			}
+		})
+	}
+}
+
+func TestPostLogRequiresUserToken(t *testing.T) {
+	r, _, baseDir := newTestRouter(t)
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+	}{
+		{"missing token", "", http.StatusUnauthorized},
+		{"malformed token", "Bearer not-a-jwt", http.StatusUnauthorized},
+		{"token without user id", "Bearer " + testToken(t, jwt.MapClaims{"name": "u1"}), http.StatusUnauthorized},
+		{"token of another user", "Bearer " + testToken(t, jwt.MapClaims{"id": "u2"}), http.StatusForbidden},
+		{"valid token", "Bearer " + testToken(t, jwt.MapClaims{"id": "u1"}), http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := uploadRequest(t, "a.log", "line\n", "")
+			req.Header.Set("Authorization", tt.authorization)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+		})
+	}
+
+	// Only the accepted upload was stored
+	entries, err := os.ReadDir(baseDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "c1" {
+		t.Errorf("storage holds %v, want only the c1 directory", entries)
+	}
+}
+
+func TestDownloadResolvesHashedStoragePath(t *testing.T) {
+	testutil.SetConfig(t, "storage.hash_subdirs", true)
+	r, _, baseDir := newTestRouter(t)
+	if w := postLog(t, r, "a.log", "hashed\n", ""); w.Code != http.StatusCreated {
+		t.Fatalf("upload status = %d, body %s", w.Code, w.Body.String())
+	}
+	if _, err := os.Stat(filepath.Join(baseDir, "c1", "e4", "a.log")); err != nil {
+		t.Fatalf("upload was not stored in its hashed subdirectory: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/client-manager/api/v1/logs/c1/a.log", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "hashed\n" {
+		t.Errorf("download = %d %q, want 200 with the uploaded content", w.Code, w.Body.String())
+	}
+}
+
+func TestUnknownRoutesUseErrorEnvelope(t *testing.T) {
+	r, _, _ := newTestRouter(t)
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		code   string
+	}{
+		{"unknown path", http.MethodGet, "/client-manager/api/v1/nothing", http.StatusNotFound, "notfound.error"},
+		{"wrong method", http.MethodDelete, "/client-manager/api/v1/logs", http.StatusMethodNotAllowed, "method_not_allowed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "application/json") {
+				t.Errorf("Content-Type = %q, want JSON", got)
+			}
+			var resp struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("body %q is not JSON: %v", w.Body.String(), err)
+			}
+			if resp.Code != tt.code || resp.Message == "" {
+				t.Errorf("body = %+v, want code %s with a message", resp, tt.code)
+			}
+		})
+	}
+}
+
+func TestListModulesRoute(t *testing.T) {
+	r, db, _ := newTestRouter(t)
+	seeded := []models.Log{
+		{ClientID: "c1", FileName: "1.log", ModuleName: "editor"},
+		{ClientID: "c2", FileName: "1.log", ModuleName: "agent"},
+	}
+	if err := db.Create(&seeded).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		want   string
+	}{
+		{"all clients", "", http.StatusOK, `[{"module_name":"agent","count":1},{"module_name":"editor","count":1}]`},
+		{"one client", "?client_id=c1", http.StatusOK, `[{"module_name":"editor","count":1}]`},
+		{"no modules", "?client_id=c9", http.StatusOK, `[]`},
+		{"invalid client", "?client_id=bad$id", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/client-manager/api/v1/logs/modules"+tt.query, nil))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.want == "" {
+				return
+			}
+			var resp struct {
+				Data json.RawMessage `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if string(resp.Data) != tt.want {
+				t.Errorf("data = %s, want %s", resp.Data, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostLogSkipsDuplicateContent(t *testing.T) {
+	r, db, baseDir := newTestRouter(t)
+	uploadsBefore := counterValue(t, "log_uploads_total", nil)
+	tests := []struct {
+		name      string
+		fileName  string
+		content   string
+		status    int
+		duplicate bool
+	}{
+		{"first upload", "a.log", "same\n", http.StatusCreated, false},
+		{"identical content under another name", "b.log", "same\n", http.StatusCreated, true},
+		{"identical content again", "a.log", "same\n", http.StatusOK, true},
+		{"different content", "c.log", "other\n", http.StatusCreated, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postLog(t, r, tt.fileName, tt.content, "")
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+			var resp struct {
+				Duplicate bool `json:"duplicate"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Duplicate != tt.duplicate {
+				t.Errorf("duplicate = %v, want %v", resp.Duplicate, tt.duplicate)
+			}
+		})
+	}
+
+	// Both names of the identical content can be fetched
+	for _, name := range []string{"a.log", "b.log"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/client-manager/api/v1/logs/c1/"+name, nil))
+		if w.Code != http.StatusOK || w.Body.String() != "same\n" {
+			t.Errorf("GET %s = %d %q, want 200 with the uploaded content", name, w.Code, w.Body.String())
+		}
+	}
+
+	files, err := os.ReadDir(filepath.Join(baseDir, "c1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+	if strings.Join(names, ",") != "a.log,b.log,c.log" {
+		t.Errorf("stored files = %v, want a.log, b.log and c.log", names)
+	}
+	a, errA := os.Stat(filepath.Join(baseDir, "c1", "a.log"))
+	b, errB := os.Stat(filepath.Join(baseDir, "c1", "b.log"))
+	if errA != nil || errB != nil || !os.SameFile(a, b) {
+		t.Errorf("b.log is not a link to the stored a.log")
+	}
+	var count int64
+	db.Model(&models.Log{}).Count(&count)
+	if count != 3 {
+		t.Errorf("log records = %d, want 3", count)
+	}
+
+	// Deduplicated uploads are still counted and audited
+	if got := counterValue(t, "log_uploads_total", nil) - uploadsBefore; got != float64(len(tests)) {
+		t.Errorf("log_uploads_total grew by %v, want %d", got, len(tests))
+	}
+	db.Model(&models.AuditEntry{}).Where("resource = ?", "log").Count(&count)
+	if count != int64(len(tests)) {
+		t.Errorf("audit entries = %d, want %d", count, len(tests))
+	}
+}
+
+func TestSwaggerRoutes(t *testing.T) {
+	testutil.SetConfig(t, "auth.required", true)
+	testutil.SetConfig(t, "auth.exempt_paths", []string{"/healthz"})
+	tests := []struct {
+		name    string
+		enabled bool
+		path    string
+		reqPath string
+		status  int
+	}{
+		{"ui at default path", true, "/swagger", "/swagger/index.html", http.StatusOK},
+		{"spec at default path", true, "/swagger", "/swagger/doc.json", http.StatusOK},
+		{"custom path", true, "/api-docs/", "/api-docs/doc.json", http.StatusOK},
+		{"old path after move", true, "/api-docs", "/swagger/doc.json", http.StatusUnauthorized},
+		{"disabled", false, "/swagger", "/swagger/doc.json", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.SetConfig(t, "swagger.enabled", tt.enabled)
+			testutil.SetConfig(t, "swagger.path", tt.path)
+			r, _, _ := newTestRouter(t)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.reqPath, nil))
+			if w.Code != tt.status {
+				t.Errorf("GET %s = %d, want %d", tt.reqPath, w.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestPostLogReportsEveryInvalidField(t *testing.T) {
+	testutil.SetConfig(t, "uploads.allowed_extensions", []string{".log"})
+	r, _, _ := newTestRouter(t)
+	tests := []struct {
+		name     string
+		fileName string
+		ifMatch  string
+		want     []string
+	}{
+		{"one invalid field", "a.exe", "", []string{"file_name"}},
+		{"two invalid fields", "a.exe", "garbage", []string{"If-Match", "file_name"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postLog(t, r, tt.fileName, "line\n", tt.ifMatch)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, http.StatusBadRequest, w.Body.String())
+			}
+			var resp struct {
+				Code   string `json:"code"`
+				Field  string `json:"field"`
+				Fields []struct {
+					Field   string `json:"field"`
+					Message string `json:"message"`
+					Rule    string `json:"rule"`
+				} `json:"fields"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Code != "validation.error" {
+				t.Errorf("code = %q, want validation.error", resp.Code)
+			}
+			var got []string
+			for _, f := range resp.Fields {
+				if f.Message == "" || f.Rule == "" {
+					t.Errorf("field %s has no message or rule", f.Field)
+				}
+				got = append(got, f.Field)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fields = %v, want %v", got, tt.want)
+			}
+			if resp.Field != tt.want[0] {
+				t.Errorf("field = %q, want the first invalid field %q", resp.Field, tt.want[0])
+			}
+		})
+	}
+}
+
+func TestPostLogErrorEnvelope(t *testing.T) {
+	testutil.SetConfig(t, "uploads.allowed_extensions", []string{".log"})
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T)
+		request func(t *testing.T) *http.Request
+		status  int
+		code    string
+	}{
+		{"not a multipart form", nil, func(t *testing.T) *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/client-manager/api/v1/logs", strings.NewReader("{}"))
+		}, http.StatusBadRequest, "argument.invalid"},
+		{"missing token", nil, func(t *testing.T) *http.Request {
+			req := uploadRequest(t, "a.log", "line\n", "")
+			req.Header.Del("Authorization")
+			return req
+		}, http.StatusUnauthorized, "auth.unauthorized"},
+		{"token of another user", nil, func(t *testing.T) *http.Request {
+			req := uploadRequest(t, "a.log", "line\n", "")
+			req.Header.Set("Authorization", "Bearer "+testToken(t, jwt.MapClaims{"id": "u2"}))
+			return req
+		}, http.StatusForbidden, "auth.forbidden"},
+		{"invalid field", nil, func(t *testing.T) *http.Request {
+			return uploadRequest(t, "a.exe", "line\n", "")
+		}, http.StatusBadRequest, "validation.error"},
+		{"body too large", func(t *testing.T) {
+			testutil.SetConfig(t, "uploads.max_body_bytes", 64)
+		}, func(t *testing.T) *http.Request {
+			return uploadRequest(t, "a.log", strings.Repeat("line\n", 100), "")
+		}, http.StatusRequestEntityTooLarge, "request.too_large"},
+		{"storage unavailable", nil, func(t *testing.T) *http.Request {
+			testutil.SetConfig(t, "storage.base_dir", filepath.Join(t.TempDir(), "missing"))
+			return uploadRequest(t, "a.log", "line\n", "")
+		}, http.StatusServiceUnavailable, "storage.unavailable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup(t)
+			}
+			r, _, _ := newTestRouter(t)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, tt.request(t))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+			var resp map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp["code"] != tt.code || resp["message"] == "" || resp["message"] == nil {
+				t.Errorf("body = %s, want code %s with a message", w.Body.String(), tt.code)
+			}
+			if _, ok := resp["error"]; ok {
+				t.Errorf("body = %s, want no error key", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/zgsm-ai/client-manager/dao"
+	"github.com/zgsm-ai/client-manager/models"
+)
+
+// Audit actions
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+// AuditActorSystem is the actor recorded for operations not triggered by a user
+const AuditActorSystem = "system"
+
+/**
+ * AuditService handles business logic for the audit trail
+ * @description
+ * - Records write operations performed by other services
+ * - Lists audit entries for administrators
+ */
+type AuditService struct {
+	auditDAO *dao.AuditDAO
+	log      *logrus.Logger
+}
+
+type ListAuditArgs struct {
+	Resource string `form:"resource"`
+	Actor    string `form:"actor"`
+	Page     int    `form:"page,default=1"`
+	PageSize int    `form:"page_size"`
+	Count    bool   `form:"count,default=true"`
+	// ApproximateTotal accepts a recently cached total instead of counting
+	ApproximateTotal bool `form:"approximate_total"`
+}
+
+/**
+ * NewAuditService creates a new AuditService instance
+ * @param {dao.AuditDAO} auditDAO - Audit data access object
+ * @param {logrus.Logger} log - Logger instance
+ * @returns {*AuditService} New AuditService instance
+ */
+func NewAuditService(auditDAO *dao.AuditDAO, log *logrus.Logger) *AuditService {
+	return &AuditService{
+		auditDAO: auditDAO,
+		log:      log,
+	}
+}
+
+/**
+ * Record appends an audit entry for a write operation
+ * @param {context.Context} ctx - Context, should carry the transaction of the audited operation
+ * @param {string} actor - Who performed the operation
+ * @param {string} action - Operation performed (create/update/delete)
+ * @param {string} resource - Type of the affected resource
+ * @param {string} resourceID - Identifier of the affected resource
+ * @param {interface{}} before - State before the operation, nil if none
+ * @param {interface{}} after - State after the operation, nil if none
+ * @returns {error} Error if the entry cannot be written
+ * @description
+ * - Serializes before/after as JSON summaries
+ * - Failing to record fails the surrounding transaction
+ */
+func (s *AuditService) Record(ctx context.Context, actor, action, resource, resourceID string, before, after interface{}) error {
+	entry := &models.AuditEntry{
+		Actor:      actor,
+		Action:     action,
+		Resource:   resource,
+		ResourceID: resourceID,
+		Before:     auditSummary(before),
+		After:      auditSummary(after),
+	}
+	if err := s.auditDAO.Create(ctx, entry); err != nil {
+		s.log.WithError(err).WithFields(logrus.Fields{
+			"actor":       actor,
+			"action":      action,
+			"resource":    resource,
+			"resource_id": resourceID,
+		}).Error("Failed to record audit entry")
+		return err
+	}
+	return nil
+}
+
+/**
+ * ListAuditEntries retrieves audit entries with filtering and pagination
+ * @param {context.Context} ctx - Context for request cancellation
+ * @param {*ListAuditArgs} args - Filters and pagination parameters
+ * @returns {[]models.AuditEntry, Paginated, error} Audit entries, pagination info and error if any
+ * @throws
+ * - Database query errors
+ */
+func (s *AuditService) ListAuditEntries(ctx context.Context, args *ListAuditArgs) ([]models.AuditEntry, Paginated, error) {
+	normalizePage(pageResourceAudit, &args.Page, &args.PageSize)
+	entries, total, err := s.auditDAO.List(ctx, args.Resource, args.Actor, args.Page, args.PageSize, countMode(args.Count, args.ApproximateTotal))
+	if err != nil {
+		s.log.WithError(err).Error("Failed to list audit entries")
+		return nil, Paginated{}, err
+	}
+	if total == nil {
+		paging := NewUncountedPaginated(args.Page, args.PageSize, len(entries))
+		if len(entries) > args.PageSize {
+			entries = entries[:args.PageSize]
+		}
+		return entries, paging, nil
+	}
+	paging := NewPaginated(args.Page, args.PageSize, *total)
+	paging.Approximate = args.ApproximateTotal
+	return entries, paging, nil
+}
+
+/**
+ * auditSummary serializes a resource state for the audit trail
+ * @param {interface{}} v - Resource state
+ * @returns {string} JSON summary, empty for nil
+ */
+func auditSummary(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
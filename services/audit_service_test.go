@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zgsm-ai/client-manager/models"
+)
+
+func TestLogWritesAreAudited(t *testing.T) {
+	s, _ := newTestLogService(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		write     func(t *testing.T)
+		actor     string
+		action    string
+		hasBefore bool
+		hasAfter  bool
+	}{
+		{
+			name: "create",
+			write: func(t *testing.T) {
+				uploadTestLog(t, s, UploadLogArgs{ClientID: "c1", UserID: "u1", FileName: "a.log"})
+			},
+			actor:    "u1",
+			action:   AuditActionCreate,
+			hasAfter: true,
+		},
+		{
+			name: "update",
+			write: func(t *testing.T) {
+				uploadTestLog(t, s, UploadLogArgs{ClientID: "c1", UserID: "u1", FileName: "a.log", LastLineNo: 5})
+			},
+			actor:     "u1",
+			action:    AuditActionUpdate,
+			hasBefore: true,
+			hasAfter:  true,
+		},
+		{
+			name: "purge",
+			write: func(t *testing.T) {
+				if _, err := s.DeleteOldLogs(ctx, "2999-01-01"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			actor:     AuditActorSystem,
+			action:    AuditActionDelete,
+			hasBefore: true,
+			hasAfter:  true,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.write(t)
+			entries, paging, err := s.auditService.ListAuditEntries(ctx, &ListAuditArgs{Resource: auditResourceLog, Page: 1, Count: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *paging.Total != int64(i+1) {
+				t.Fatalf("audit entries = %d, want %d", *paging.Total, i+1)
+			}
+			got := entries[0]
+			if got.Actor != tt.actor || got.Action != tt.action {
+				t.Errorf("entry = %s by %s, want %s by %s", got.Action, got.Actor, tt.action, tt.actor)
+			}
+			if (got.Before != "") != tt.hasBefore || (got.After != "") != tt.hasAfter {
+				t.Errorf("before = %q, after = %q, want before %v and after %v", got.Before, got.After, tt.hasBefore, tt.hasAfter)
+			}
+		})
+	}
+}
+
+func TestAuditFailureRollsBackWrite(t *testing.T) {
+	s, db := newTestLogService(t)
+	if err := db.Migrator().DropTable(&models.AuditEntry{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := s.CreateLog(context.Background(), &UploadLogArgs{ClientID: "c1", UserID: "u1", FileName: "a.log"}); err == nil {
+		t.Fatal("CreateLog succeeded without an audit table")
+	}
+	var count int64
+	db.Model(&models.Log{}).Count(&count)
+	if count != 0 {
+		t.Errorf("log was stored although its audit entry failed")
+	}
+}
+
+func TestListAuditEntriesFilters(t *testing.T) {
+	s, _ := newTestLogService(t)
+	ctx := context.Background()
+	for _, entry := range []struct{ actor, resource string }{
+		{"u1", "log"}, {"u1", "config"}, {"u2", "log"}, {AuditActorSystem, "log"},
+	} {
+		if err := s.auditService.Record(ctx, entry.actor, AuditActionCreate, entry.resource, "1", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name            string
+		resource, actor string
+		want            int64
+	}{
+		{"all", "", "", 4},
+		{"by resource", "log", "", 3},
+		{"by actor", "", "u1", 2},
+		{"by resource and actor", "config", "u1", 1},
+		{"no match", "config", "u2", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, paging, err := s.auditService.ListAuditEntries(ctx, &ListAuditArgs{Resource: tt.resource, Actor: tt.actor, Page: 1, Count: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *paging.Total != tt.want || int64(len(entries)) != tt.want {
+				t.Errorf("got %d entries, total %d, want %d", len(entries), *paging.Total, tt.want)
+			}
+			for _, e := range entries {
+				if (tt.resource != "" && e.Resource != tt.resource) || (tt.actor != "" && e.Actor != tt.actor) {
+					t.Errorf("entry %s/%s does not match the filters", e.Resource, e.Actor)
+				}
+			}
+		})
+	}
+}
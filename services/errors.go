@@ -1,14 +1,32 @@
 package services
 
+import (
+	"errors"
+	"strings"
+
+	"github.com/zgsm-ai/client-manager/internal"
+)
+
+// Validation rules reported with validation errors, clients branch on them
+const (
+	RuleRequired = "required" // value is missing
+	RuleFormat   = "format"   // value is malformed
+	RuleEnum     = "enum"     // value is not one of the allowed values
+	RuleRange    = "range"    // value is outside the allowed range
+	RuleMaxSize  = "max_size" // value is too large
+)
+
 /**
  * ValidationError represents a validation error
  * @description
- * - Contains field name and error message
+ * - Contains field name and a localizable error message
+ * - Rule names the failed check, one of the Rule* constants
  * - Used for input validation failures
  */
 type ValidationError struct {
 	Field   string
-	Message string
+	Message internal.Message
+	Rule    string
 }
 
 /**
@@ -16,7 +34,58 @@ type ValidationError struct {
  * @returns {string} Error message
  */
 func (e *ValidationError) Error() string {
-	return e.Message
+	return e.Message.String()
+}
+
+/**
+ * ValidationErrors collects several validation errors of one request
+ * @description
+ * - Lets a request report every invalid field at once
+ * - Use Err to return it, an empty collection is no error
+ */
+type ValidationErrors []*ValidationError
+
+/**
+ * Add appends validation errors to the collection
+ * @param {error} err - nil, a *ValidationError or ValidationErrors
+ * @returns {error} err itself when it is not a validation error, nil otherwise
+ */
+func (e *ValidationErrors) Add(err error) error {
+	var one *ValidationError
+	var many ValidationErrors
+	switch {
+	case err == nil:
+	case errors.As(err, &many):
+		*e = append(*e, many...)
+	case errors.As(err, &one):
+		*e = append(*e, one)
+	default:
+		return err
+	}
+	return nil
+}
+
+/**
+ * Err returns the collection as an error
+ * @returns {error} nil when empty, the collection otherwise
+ */
+func (e ValidationErrors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+/**
+ * Error returns the messages of all collected errors
+ * @returns {string} Messages joined by "; "
+ */
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Message.String()
+	}
+	return strings.Join(messages, "; ")
 }
 
 /**
@@ -26,7 +95,7 @@ func (e *ValidationError) Error() string {
  * - Contains error message
  */
 type ConflictError struct {
-	Message string
+	Message internal.Message
 }
 
 /**
@@ -34,7 +103,26 @@ type ConflictError struct {
  * @returns {string} Error message
  */
 func (e *ConflictError) Error() string {
-	return e.Message
+	return e.Message.String()
+}
+
+/**
+ * PreconditionFailedError represents a failed conditional request
+ * @description
+ * - Used when If-Match does not match the current resource state
+ * - ETag is the current entity tag, empty if the resource does not exist
+ */
+type PreconditionFailedError struct {
+	Message internal.Message
+	ETag    string
+}
+
+/**
+ * Error returns the error message
+ * @returns {string} Error message
+ */
+func (e *PreconditionFailedError) Error() string {
+	return e.Message.String()
 }
 
 /**
@@ -44,7 +132,7 @@ func (e *ConflictError) Error() string {
  * - Contains error message
  */
 type NotFoundError struct {
-	Message string
+	Message internal.Message
 }
 
 /*
@@ -53,5 +141,98 @@ type NotFoundError struct {
   - @returns {string} Error message
 */
 func (e *NotFoundError) Error() string {
-	return e.Message
-}
\ No newline at end of file
+	return e.Message.String()
+}
+
+/**
+ * ArgumentError represents a request that could not be decoded
+ * @description
+ * - Used for malformed multipart forms and JSON arguments
+ * - Err is the decoding error, its text is included in the message
+ */
+type ArgumentError struct {
+	Err error
+}
+
+/**
+ * Error returns the error message
+ * @returns {string} Error message
+ */
+func (e *ArgumentError) Error() string {
+	return e.Err.Error()
+}
+
+/**
+ * Unwrap returns the decoding error
+ * @returns {error} Wrapped error
+ */
+func (e *ArgumentError) Unwrap() error {
+	return e.Err
+}
+
+/**
+ * UnauthorizedError represents a request without valid credentials
+ * @description
+ * - Used when a bearer token is missing or invalid
+ * - Contains error message
+ */
+type UnauthorizedError struct {
+	Message internal.Message
+}
+
+/**
+ * Error returns the error message
+ * @returns {string} Error message
+ */
+func (e *UnauthorizedError) Error() string {
+	return e.Message.String()
+}
+
+/**
+ * ForbiddenError represents a request the caller may not make
+ * @description
+ * - Used when the credentials do not cover the requested resource
+ * - Contains error message
+ */
+type ForbiddenError struct {
+	Message internal.Message
+}
+
+/**
+ * Error returns the error message
+ * @returns {string} Error message
+ */
+func (e *ForbiddenError) Error() string {
+	return e.Message.String()
+}
+
+/**
+ * StorageError represents a failure of the file storage
+ * @description
+ * - Unavailable is set when the storage directory cannot be used at all
+ * - Err is the underlying file system error, it is logged but not returned to clients
+ */
+type StorageError struct {
+	Message     internal.Message
+	Unavailable bool
+	Err         error
+}
+
+/**
+ * Error returns the error message
+ * @returns {string} Error message
+ */
+func (e *StorageError) Error() string {
+	if e.Err == nil {
+		return e.Message.String()
+	}
+	return e.Message.String() + ": " + e.Err.Error()
+}
+
+/**
+ * Unwrap returns the file system error
+ * @returns {error} Wrapped error
+ */
+func (e *StorageError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/zgsm-ai/client-manager/internal"
+	"github.com/zgsm-ai/client-manager/internal/testutil"
+)
+
+// gzipped compresses data
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestValidationRules(t *testing.T) {
+	s, _ := newTestLogService(t)
+	testutil.SetConfig(t, "uploads.allowed_extensions", []string{".log"})
+	testutil.SetConfig(t, "uploads.gzip.decompress", true)
+	testutil.SetConfig(t, "uploads.gzip.max_decompressed_bytes", 4)
+
+	tests := []struct {
+		name  string
+		field string
+		rule  string
+		run   func() error
+	}{
+		{"missing value", "client_id", RuleRequired, func() error {
+			return s.ValidateUpload(&UploadLogArgs{UserID: "u1", FileName: "a.log"})
+		}},
+		{"malformed value", "end_date", RuleFormat, func() error {
+			_, err := s.GetLogStats(context.Background(), &LogStatsArgs{EndDate: "2024/01/01"})
+			return err
+		}},
+		{"value not allowed", "file_name", RuleEnum, func() error {
+			return s.ValidateUpload(&UploadLogArgs{ClientID: "c1", UserID: "u1", FileName: "a.exe"})
+		}},
+		{"value out of range", "start_date", RuleRange, func() error {
+			_, err := s.GetLogStats(context.Background(), &LogStatsArgs{StartDate: "2024-02-01", EndDate: "2024-01-01"})
+			return err
+		}},
+		{"value too large", "logfile", RuleMaxSize, func() error {
+			_, _, err := s.CountLines(bytes.NewReader(gzipped(t, []byte("line\nline\n"))), "a.log.gz")
+			return err
+		}},
+	}
+	seen := map[string]string{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertRule(t, tt.run(), tt.field, tt.rule)
+		})
+		if other, ok := seen[tt.rule]; ok {
+			t.Errorf("rule %q reported for both %q and %q", tt.rule, other, tt.name)
+		}
+		seen[tt.rule] = tt.name
+	}
+}
+
+func TestValidationErrorsAdd(t *testing.T) {
+	required := &ValidationError{Field: "client_id", Message: internal.NewMessage(internal.MsgFieldRequired, "field", "client_id"), Rule: RuleRequired}
+	invalid := &ValidationError{Field: "file_name", Message: internal.NewMessage(internal.MsgFieldInvalid, "field", "file_name"), Rule: RuleFormat}
+	other := errors.New("database is locked")
+
+	tests := []struct {
+		name     string
+		add      []error
+		returned error
+		want     string
+	}{
+		{"nothing", []error{nil}, nil, ""},
+		{"single", []error{required}, nil, "client_id is required"},
+		{"collection", []error{ValidationErrors{required, invalid}}, nil, "client_id is required; file_name is invalid"},
+		{"other error", []error{required, other}, other, "client_id is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errs ValidationErrors
+			var returned error
+			for _, err := range tt.add {
+				if r := errs.Add(err); r != nil {
+					returned = r
+				}
+			}
+			if returned != tt.returned {
+				t.Errorf("Add returned %v, want %v", returned, tt.returned)
+			}
+			err := errs.Err()
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Err() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Err() = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/zgsm-ai/client-manager/dao"
+	"github.com/zgsm-ai/client-manager/internal/testutil"
+	"github.com/zgsm-ai/client-manager/models"
+)
+
+// newTestLogService creates a LogService on a fresh database and storage directory
+func newTestLogService(t *testing.T) (*LogService, *gorm.DB) {
+	t.Helper()
+	testutil.SetConfig(t, "storage.base_dir", t.TempDir())
+	db := testutil.NewDB(t, nil, &models.Log{}, &models.AuditEntry{})
+	log := testutil.NewLogger()
+	auditService := NewAuditService(dao.NewAuditDAO(db, log), log)
+	return NewLogService(dao.NewLogDAO(db, log), auditService, log), db
+}
+
+// uploadTestLog stores a log record and its file like PostLog does
+func uploadTestLog(t *testing.T, s *LogService, args UploadLogArgs) *models.Log {
+	t.Helper()
+	saved, err := s.SaveLog(context.Background(), &args, strings.NewReader("line\n"))
+	if err != nil {
+		t.Fatalf("save log %s/%s: %v", args.ClientID, args.FileName, err)
+	}
+	return saved.Log
+}
+
+// stagedFiles lists the temporary upload files left in a directory
+func stagedFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	found, err := filepath.Glob(filepath.Join(dir, ".*.tmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return found
+}
+
+// fileExists reports whether a path exists
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -1,8 +1,13 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -16,10 +21,14 @@ import (
 
 // AppContext holds all the core application objects
 type AppContext struct {
-	DB         *gorm.DB
-	Logger     *logrus.Logger
-	LogDAO     *dao.LogDAO
-	LogService *LogService
+	DB            *gorm.DB
+	Logger        *logrus.Logger
+	LogDAO        *dao.LogDAO
+	AuditDAO      *dao.AuditDAO
+	LogService    *LogService
+	AuditService  *AuditService
+	SearchService *SearchService
+	Scheduler     *internal.Scheduler
 }
 
 // InitializeApp initializes all core application objects and returns AppContext
@@ -29,9 +38,12 @@ type AppContext struct {
  * @description
  * - Initializes database connection
  * - Initializes Prometheus metrics
+ * - Initializes token verification
  * - Creates all DAO objects
  * - Creates all service objects
  * - Creates all controller objects
+ * - Runs the startup self-check
+ * - Starts background jobs on the scheduler
  * @throws
  * - Database initialization error
  */
@@ -40,7 +52,8 @@ func InitializeApp() (*AppContext, error) {
 	logger := logrus.New()
 	logger.SetFormatter(&logrus.JSONFormatter{})
 	logger.SetOutput(os.Stdout)
-	logger.SetLevel(logrus.InfoLevel)
+	logger.SetLevel(internal.GetLogLevel())
+	logrus.SetLevel(internal.GetLogLevel())
 
 	// Initialize database
 	db, err := internal.InitDB()
@@ -51,23 +64,64 @@ func InitializeApp() (*AppContext, error) {
 	// Initialize Prometheus metrics
 	internal.InitMetrics()
 
+	// Initialize token verification
+	internal.InitAuth()
+
 	// Initialize DAOs
 	logDAO := dao.NewLogDAO(db, logger)
+	auditDAO := dao.NewAuditDAO(db, logger)
 
 	// Initialize services
-	logService := NewLogService(logDAO, logger)
+	auditService := NewAuditService(auditDAO, logger)
+	logService := NewLogService(logDAO, auditService, logger)
+	searchService := NewSearchService(logDAO, auditDAO, logger)
+
+	// Verify the database and log a startup summary before serving
+	if err := internal.SelfCheck(db, logger); err != nil {
+		return nil, fmt.Errorf("startup self-check failed: %w", err)
+	}
+
+	// Start background jobs
+	scheduler := internal.NewScheduler(logger)
+	scheduleLogRetention(scheduler, logService, logger)
 
 	// Create and return app context
 	appContext := &AppContext{
-		DB:         db,
-		Logger:     logger,
-		LogDAO:     logDAO,
-		LogService: logService,
+		DB:            db,
+		Logger:        logger,
+		LogDAO:        logDAO,
+		AuditDAO:      auditDAO,
+		LogService:    logService,
+		AuditService:  auditService,
+		SearchService: searchService,
+		Scheduler:     scheduler,
 	}
 
 	return appContext, nil
 }
 
+/**
+ * scheduleLogRetention registers the periodic purge of old log records
+ * @param {*internal.Scheduler} scheduler - Background job scheduler
+ * @param {*LogService} logService - Log service performing the purge
+ * @param {*logrus.Logger} logger - Application logger
+ * @description
+ * - Does nothing unless log.retention.max_age is positive
+ * - Runs every log.retention.interval and deletes records older than max_age with their files
+ */
+func scheduleLogRetention(scheduler *internal.Scheduler, logService *LogService, logger *logrus.Logger) {
+	maxAge, interval := internal.GetLogRetention()
+	if maxAge <= 0 {
+		return
+	}
+	scheduler.Every("log-retention", interval, func(ctx context.Context) {
+		beforeDate := internal.Now().Add(-maxAge).Format("2006-01-02")
+		if _, err := logService.DeleteOldLogs(ctx, beforeDate); err != nil {
+			logger.WithError(err).Warn("Log retention run failed")
+		}
+	})
+}
+
 // StartServer starts the HTTP server
 /**
  * Start HTTP server
@@ -76,9 +130,11 @@ func InitializeApp() (*AppContext, error) {
  * @description
  * - Gets server port from configuration
  * - Records startup time
- * - Starts the HTTP server
+ * - Starts the HTTP server on TCP, or on a Unix socket for unix:// addresses
+ * - Blocks until SIGINT or SIGTERM is received, then drains in-flight requests
  * @throws
  * - Server start error
+ * - Server shutdown error
  */
 func StartServer(r *gin.Engine, logger *logrus.Logger) error {
 	// Get port from configuration
@@ -90,5 +146,35 @@ func StartServer(r *gin.Engine, logger *logrus.Logger) error {
 	// Record startup time
 	utils.SetStartupTime(time.Now())
 
-	return r.Run(listenAddr)
+	ln, err := internal.Listen(listenAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", listenAddr, err)
+	}
+
+	srv := &http.Server{
+		Handler: r,
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(ln)
+	}()
+
+	// Wait for termination signal or server failure
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	select {
+	case err := <-serveErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case sig := <-quit:
+		logger.Infof("Received signal %s, shutting down server", sig)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), internal.GetShutdownTimeout())
+	defer cancel()
+	return srv.Shutdown(ctx)
 }
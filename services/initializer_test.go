@@ -0,0 +1,65 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zgsm-ai/client-manager/internal"
+	"github.com/zgsm-ai/client-manager/internal/testutil"
+	"github.com/zgsm-ai/client-manager/models"
+)
+
+func TestScheduleLogRetention(t *testing.T) {
+	s, db := newTestLogService(t)
+	testutil.SetConfig(t, "log.retention.max_age", "24h")
+	testutil.SetConfig(t, "log.retention.interval", "5ms")
+
+	old := uploadTestLog(t, s, UploadLogArgs{ClientID: "c1", UserID: "u1", FileName: "old.log"})
+	uploadTestLog(t, s, UploadLogArgs{ClientID: "c1", UserID: "u1", FileName: "new.log"})
+	if err := db.Model(&models.Log{}).Where("id = ?", old.ID).
+		UpdateColumn("updated_at", internal.Now().AddDate(0, 0, -3)).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	scheduler := internal.NewScheduler(testutil.NewLogger())
+	scheduleLogRetention(scheduler, s, testutil.NewLogger())
+	deadline := time.Now().Add(time.Second)
+	for fileExists(s.StoragePath("c1", "old.log")) && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	scheduler.Stop()
+
+	tests := []struct {
+		file string
+		kept bool
+	}{
+		{"old.log", false},
+		{"new.log", true},
+	}
+	for _, tt := range tests {
+		var count int64
+		db.Model(&models.Log{}).Where("file_name = ?", tt.file).Count(&count)
+		if got := count == 1; got != tt.kept {
+			t.Errorf("record %s kept = %v, want %v", tt.file, got, tt.kept)
+		}
+		if got := fileExists(s.StoragePath("c1", tt.file)); got != tt.kept {
+			t.Errorf("file %s kept = %v, want %v", tt.file, got, tt.kept)
+		}
+	}
+}
+
+func TestScheduleLogRetentionDisabled(t *testing.T) {
+	s, _ := newTestLogService(t)
+	testutil.SetConfig(t, "log.retention.max_age", "0s")
+	testutil.SetConfig(t, "log.retention.interval", "5ms")
+	uploadTestLog(t, s, UploadLogArgs{ClientID: "c1", UserID: "u1", FileName: "a.log"})
+
+	scheduler := internal.NewScheduler(testutil.NewLogger())
+	scheduleLogRetention(scheduler, s, testutil.NewLogger())
+	time.Sleep(20 * time.Millisecond)
+	scheduler.Stop()
+
+	if !fileExists(s.StoragePath("c1", "a.log")) {
+		t.Errorf("retention ran although log.retention.max_age is 0")
+	}
+}
@@ -1,13 +1,23 @@
 package services
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/zgsm-ai/client-manager/dao"
+	"github.com/zgsm-ai/client-manager/internal"
 	"github.com/zgsm-ai/client-manager/models"
 )
 
@@ -17,18 +27,30 @@ import (
  * - Implements log processing business rules
  * - Validates log data
  * - Handles different log types
+ * - Records every write in the audit trail
  */
 type LogService struct {
-	logDAO *dao.LogDAO
-	log    *logrus.Logger
+	logDAO       *dao.LogDAO
+	auditService *AuditService
+	log          *logrus.Logger
 }
 
+// auditResourceLog is the audit resource name of log records
+const auditResourceLog = "log"
+
 type UploadLogArgs struct {
 	ClientID    string `json:"client_id"`
 	UserID      string `json:"user_id"`
 	FileName    string `json:"file_name"`
+	ModuleName  string `json:"module_name"`
 	FirstLineNo int64  `json:"first_line_no"`
 	LastLineNo  int64  `json:"end_line_no"`
+	SizeBytes   int64  `json:"-"` // taken from the uploaded file, not from the client
+	ContentHash string `json:"-"` // computed from the uploaded file, not from the client
+	LineCount   int64  `json:"-"` // counted from the uploaded file, 0 when not counted
+	Compressed  bool   `json:"-"` // whether the uploaded file is gzip compressed
+	IfMatch     *int64 `json:"-"` // LastLineNo the client expects, from the If-Match header
+	IfMatchAny  bool   `json:"-"` // If-Match: *, the log must already exist
 }
 
 type ListLogsArgs struct {
@@ -36,7 +58,10 @@ type ListLogsArgs struct {
 	UserId   string `form:"user_id"`
 	FileName string `form:"file_name"`
 	Page     int    `form:"page,default=1"`
-	PageSize int    `form:"page_size,default=10"`
+	PageSize int    `form:"page_size"`
+	Count    bool   `form:"count,default=true"`
+	// ApproximateTotal accepts a recently cached total instead of counting
+	ApproximateTotal bool `form:"approximate_total"`
 }
 
 type GetLogArgs struct {
@@ -45,28 +70,43 @@ type GetLogArgs struct {
 	FileName string `form:"file_name"`
 }
 
-type LogStats struct {
-	FirstLineNo int64 //首行编号
-	LastLineNo  int64 //尾行编号
+type LogStatsArgs struct {
+	StartDate string `form:"start_date"`
+	EndDate   string `form:"end_date"`
 }
 
-type Paginated struct {
-	Page       int64 `json:"page"`
-	PageSize   int64 `json:"page_size"`
-	Total      int64 `json:"total"`
-	TotalPages int64 `json:"total_pages"`
+/**
+ * LogStats summarizes log uploads over a date range
+ * @description
+ * - StartDate/EndDate echo the effective range, both inclusive (YYYY-MM-DD)
+ * - Daily lists days with at least one log, ordered by date
+ * - Clients lists per-client totals, most files first
+ * - ActiveClients is the number of clients with logs in the range
+ */
+type LogStats struct {
+	StartDate     string                  `json:"start_date"`
+	EndDate       string                  `json:"end_date"`
+	TotalLogs     int64                   `json:"total_logs"`
+	ActiveClients int64                   `json:"active_clients"`
+	Daily         []models.DailyLogCount  `json:"daily"`
+	Clients       []models.ClientLogCount `json:"clients"`
 }
 
+// defaultStatsDays is the length of the stats range when no start date is given
+const defaultStatsDays = 7
+
 /**
  * NewLogService creates a new LogService instance
  * @param {dao.LogDAO} logDAO - Log data access object
+ * @param {*AuditService} auditService - Audit service recording writes
  * @param {logrus.Logger} log - Logger instance
  * @returns {*LogService} New LogService instance
  */
-func NewLogService(logDAO *dao.LogDAO, log *logrus.Logger) *LogService {
+func NewLogService(logDAO *dao.LogDAO, auditService *AuditService, log *logrus.Logger) *LogService {
 	return &LogService{
-		logDAO: logDAO,
-		log:    log,
+		logDAO:       logDAO,
+		auditService: auditService,
+		log:          log,
 	}
 }
 
@@ -74,53 +114,388 @@ func NewLogService(logDAO *dao.LogDAO, log *logrus.Logger) *LogService {
  * CreateLog creates a new log record
  * @param {context.Context} ctx - Context for request cancellation
  * @param {map[string]interface{}} data - Log data
- * @returns {*models.Log, error} Created log and error if any
+ * @returns {*models.Log, bool, error} Stored log, true if newly created, and error if any
  * @description
  * - Validates log data
- * - Creates log record
+ * - Creates log record, or updates the existing record for the same client and file
+ * - Evicts the user's least recently updated logs when a new record would exceed
+ *   log.max_files_per_user, removing their files after the outermost commit so a rolled
+ *   back request transaction keeps them
+ * - Records the writes in the audit trail within the same transaction
+ * - With IfMatch only applies when the stored LastLineNo still equals it
+ * - With IfMatchAny only applies when the log already exists
  * - Logs creation operation
+ * - Does not write the file, see SaveLog
  * @throws
  * - Validation errors for invalid data
+ * - PreconditionFailedError when IfMatch is stale or the log does not exist
+ * - PreconditionFailedError when IfMatchAny is set and the log does not exist
  * - Database creation errors
  */
-func (s *LogService) CreateLog(ctx context.Context, args *UploadLogArgs) (*models.Log, error) {
-	// Validate and extract log data
-	err := s.validate(args)
+func (s *LogService) CreateLog(ctx context.Context, args *UploadLogArgs) (*models.Log, bool, error) {
+	if err := s.validateArgs(args); err != nil {
+		return nil, false, err
+	}
+	return s.createLog(ctx, args, nil)
+}
+
+/**
+ * SavedLog describes a log stored by SaveLog
+ * @description
+ * - Duplicate is set when identical content of the user was already stored,
+ *   the file was then linked or kept instead of written again
+ * - Written is the number of bytes written to storage, 0 for duplicates
+ */
+type SavedLog struct {
+	Log       *models.Log
+	Created   bool
+	Duplicate bool
+	Written   int64
+}
+
+/**
+ * SaveLog stores an uploaded log file together with its record
+ * @param {context.Context} ctx - Context for request cancellation
+ * @param {*UploadLogArgs} args - Upload arguments, ContentHash enables duplicate detection
+ * @param {io.Reader} content - Uploaded file
+ * @returns {*SavedLog, error} Stored log and error if any
+ * @description
+ * - Writes the file to a temporary name next to its destination before touching the database,
+ *   so a failed write neither stores a record nor evicts other logs
+ * - Content already stored for the user is not written again: the file is kept when it is the
+ *   requested one, otherwise the stored file is hard linked, falling back to a copy
+ * - Always stores the record for the requested client and file like CreateLog, including its
+ *   audit entries, so duplicates can be fetched under their own name
+ * - Moves the file into place once the outermost transaction has committed,
+ *   the evicted files are only removed after that
+ * - Removes the temporary file when the record is not stored or the request transaction rolls back
+ * @throws
+ * - Errors of CreateLog
+ * - StorageError when the file cannot be written or moved into place
+ */
+func (s *LogService) SaveLog(ctx context.Context, args *UploadLogArgs, content io.Reader) (*SavedLog, error) {
+	if err := s.validateArgs(args); err != nil {
+		return nil, err
+	}
+	destPath := s.StoragePath(args.ClientID, args.FileName)
+	duplicate, err := s.findDuplicate(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	saved := &SavedLog{Duplicate: duplicate != nil}
+	var staged *stagedFile
+	switch {
+	case duplicate != nil && duplicate.ClientID == args.ClientID && duplicate.FileName == args.FileName:
+		// The requested file already holds this content
+	case duplicate != nil:
+		if staged, err = s.stageLink(destPath, s.StoragePath(duplicate.ClientID, duplicate.FileName)); err != nil {
+			s.log.WithError(err).WithField("path", destPath).Warn("Failed to link duplicate log file, copying it")
+			saved.Duplicate = false
+		}
+	}
+	if staged == nil && !saved.Duplicate {
+		if staged, saved.Written, err = s.stageFile(destPath, content); err != nil {
+			s.log.WithError(err).WithFields(logrus.Fields{
+				"client_id": args.ClientID,
+				"file_name": args.FileName,
+			}).Error("Failed to write log file")
+			return nil, err
+		}
+	}
+	if saved.Log, saved.Created, err = s.createLog(ctx, args, staged); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+/**
+ * validateArgs validates upload arguments and logs rejections
+ * @param {*UploadLogArgs} args - Upload arguments, the client ID is normalized in place
+ * @returns {error} Validation errors, nil if valid
+ */
+func (s *LogService) validateArgs(args *UploadLogArgs) error {
+	err := s.ValidateUpload(args)
 	if err != nil {
 		s.log.WithError(err).WithFields(logrus.Fields{
 			"client_id": args.ClientID,
 			"user_id":   args.UserID,
 			"file_name": args.FileName,
 		}).Error("Invalid arguments")
-		return nil, err
 	}
+	return err
+}
 
+/**
+ * createLog stores a validated log record and publishes its staged file
+ * @param {context.Context} ctx - Context for request cancellation
+ * @param {*UploadLogArgs} args - Validated upload arguments
+ * @param {*stagedFile} staged - File to move into place after the commit, nil if there is none
+ * @returns {*models.Log, bool, error} Stored log, true if newly created, and error if any
+ */
+func (s *LogService) createLog(ctx context.Context, args *UploadLogArgs, staged *stagedFile) (*models.Log, bool, error) {
 	// Create log
 	log := &models.Log{
-		ClientID:  args.ClientID,
-		UserID:    args.UserID,
-		FileName:  args.FileName,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ClientID:    args.ClientID,
+		UserID:      args.UserID,
+		FileName:    args.FileName,
+		ModuleName:  strings.TrimSpace(args.ModuleName),
+		FirstLineNo: args.FirstLineNo,
+		LastLineNo:  args.LastLineNo,
+		SizeBytes:   args.SizeBytes,
+		ContentHash: args.ContentHash,
+		LineCount:   args.LineCount,
+		Compressed:  args.Compressed,
 	}
-	// Create log
-	err = s.logDAO.Upsert(ctx, log)
+	// Create log and audit entry atomically
+	var created bool
+	var evicted []models.Log
+	err := s.logDAO.Transaction(ctx, func(ctx context.Context) error {
+		existing, err := s.logDAO.GetLog(ctx, log.ClientID, log.FileName)
+		if err != nil {
+			return err
+		}
+		if args.IfMatch != nil && (existing == nil || existing.LastLineNo != *args.IfMatch) {
+			return logChanged(existing)
+		}
+		if args.IfMatchAny && existing == nil {
+			return logChanged(nil)
+		}
+		if existing == nil {
+			if evicted, err = s.evictForUser(ctx, log.UserID); err != nil {
+				return err
+			}
+		}
+		created, err = s.logDAO.Upsert(ctx, log, args.IfMatch)
+		if errors.Is(err, dao.ErrLastLineNoMismatch) {
+			// Another upload appended since the check above
+			if existing, err = s.logDAO.GetLog(ctx, log.ClientID, log.FileName); err != nil {
+				return err
+			}
+			return logChanged(existing)
+		}
+		if err != nil {
+			return err
+		}
+		action, before := AuditActionCreate, interface{}(nil)
+		if existing != nil {
+			action, before = AuditActionUpdate, existing
+		}
+		return s.auditService.Record(ctx, log.UserID, action, auditResourceLog,
+			strconv.FormatUint(uint64(log.ID), 10), before, log)
+	})
 	if err != nil {
 		s.log.WithError(err).WithFields(logrus.Fields{
 			"client_id": log.ClientID,
 			"user_id":   log.UserID,
 			"file_name": log.FileName,
 		}).Error("Failed to create log")
-		return nil, err
+		staged.discard()
+		return nil, false, err
+	}
+	// Without a request transaction the hook runs before AfterCommit returns
+	var placeErr error
+	internal.AfterRollback(ctx, staged.discard)
+	internal.AfterCommit(ctx, func() {
+		if placeErr = staged.commit(); placeErr != nil {
+			s.log.WithError(placeErr).WithField("path", staged.destPath).Error("Failed to move log file into place")
+			staged.discard()
+			return
+		}
+		s.removeFiles(evicted)
+	})
+	if placeErr != nil {
+		return nil, false, &StorageError{Message: internal.NewMessage(internal.MsgFileSaveFailed), Err: placeErr}
 	}
 
 	s.log.WithFields(logrus.Fields{
 		"client_id": log.ClientID,
 		"user_id":   log.UserID,
 		"file_name": log.FileName,
+		"created":   created,
+		"evicted":   len(evicted),
 	}).Info("Log created successfully")
 
-	return log, nil
+	return log, created, nil
+}
+
+/**
+ * stagedFile is an uploaded file written next to its destination but not yet visible there
+ * @description
+ * - A nil stagedFile commits and discards nothing
+ */
+type stagedFile struct {
+	tmpPath  string
+	destPath string
+	log      *logrus.Logger
+}
+
+/**
+ * stageFile writes content to a temporary file in the directory of destPath
+ * @param {string} destPath - Final storage path
+ * @param {io.Reader} content - File content
+ * @returns {*stagedFile, int64, error} Staged file, bytes written and error if any
+ * @description
+ * - Syncs the temporary file so the renamed file survives a crash
+ * - Removes the temporary file again on any error
+ * @throws
+ * - StorageError when the file cannot be created or written
+ */
+func (s *LogService) stageFile(destPath string, content io.Reader) (*stagedFile, int64, error) {
+	tmp, err := createStagingFile(destPath)
+	if err != nil {
+		return nil, 0, err
+	}
+	written, err := io.Copy(tmp, content)
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, 0, &StorageError{Message: internal.NewMessage(internal.MsgFileSaveFailed), Err: err}
+	}
+	return &stagedFile{tmpPath: tmp.Name(), destPath: destPath, log: s.log}, written, nil
+}
+
+/**
+ * stageLink hard links a stored file to a temporary name in the directory of destPath
+ * @param {string} destPath - Final storage path
+ * @param {string} srcPath - Stored file with the same content
+ * @returns {*stagedFile, error} Staged file and error if any
+ * @description
+ * - Both names share the data, removing either one later keeps the other
+ * @throws
+ * - StorageError when the directory cannot be created
+ * - Link errors, e.g. when the file system does not support hard links
+ */
+func (s *LogService) stageLink(destPath, srcPath string) (*stagedFile, error) {
+	tmp, err := createStagingFile(destPath)
+	if err != nil {
+		return nil, err
+	}
+	tmp.Close()
+	// Replace the placeholder, its random name keeps concurrent uploads apart
+	if err := os.Remove(tmp.Name()); err != nil {
+		return nil, err
+	}
+	if err := os.Link(srcPath, tmp.Name()); err != nil {
+		return nil, err
+	}
+	return &stagedFile{tmpPath: tmp.Name(), destPath: destPath, log: s.log}, nil
+}
+
+/**
+ * createStagingFile creates an empty temporary file in the directory of destPath
+ * @param {string} destPath - Final storage path
+ * @returns {*os.File, error} Open temporary file and error if any
+ * @throws
+ * - StorageError when the directory or the file cannot be created
+ */
+func createStagingFile(destPath string) (*os.File, error) {
+	dir := filepath.Dir(destPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, &StorageError{Message: internal.NewMessage(internal.MsgFileCreateFailed), Err: err}
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(destPath)+".*.tmp")
+	if err != nil {
+		return nil, &StorageError{Message: internal.NewMessage(internal.MsgFileCreateFailed), Err: err}
+	}
+	return tmp, nil
+}
+
+/**
+ * commit moves the staged file to its destination, replacing an older version
+ * @returns {error} Rename error if any
+ */
+func (f *stagedFile) commit() error {
+	if f == nil {
+		return nil
+	}
+	return os.Rename(f.tmpPath, f.destPath)
+}
+
+/**
+ * discard removes the staged file, it is a no-op once committed
+ */
+func (f *stagedFile) discard() {
+	if f == nil {
+		return
+	}
+	if err := os.Remove(f.tmpPath); err != nil && !os.IsNotExist(err) {
+		f.log.WithError(err).WithField("path", f.tmpPath).Warn("Failed to remove staged log file")
+	}
+}
+
+/**
+ * evictForUser deletes the least recently updated logs of a user to make room for a new one
+ * @param {context.Context} ctx - Context carrying the upload transaction
+ * @param {string} userID - User identifier
+ * @returns {[]models.Log, error} Deleted records and error if any
+ * @description
+ * - Does nothing unless log.max_files_per_user is positive
+ * - Deletes enough records that the new one keeps the user at the cap
+ * - Records each deletion in the audit trail as a system action
+ */
+func (s *LogService) evictForUser(ctx context.Context, userID string) ([]models.Log, error) {
+	max := internal.GetLogMaxFilesPerUser()
+	if max <= 0 {
+		return nil, nil
+	}
+	count, err := s.logDAO.CountByUser(ctx, userID)
+	if err != nil || count < int64(max) {
+		return nil, err
+	}
+
+	evicted, err := s.logDAO.LeastRecentByUser(ctx, userID, int(count)-max+1)
+	if err != nil {
+		return nil, err
+	}
+	for i := range evicted {
+		if err := s.logDAO.DeleteLog(ctx, evicted[i].ID); err != nil {
+			return nil, err
+		}
+		if err := s.auditService.Record(ctx, AuditActorSystem, AuditActionDelete, auditResourceLog,
+			strconv.FormatUint(uint64(evicted[i].ID), 10), &evicted[i], nil); err != nil {
+			return nil, err
+		}
+	}
+	return evicted, nil
+}
+
+/**
+ * removeFiles deletes the stored files of log records
+ * @param {[]models.Log} logs - Deleted log records
+ * @description
+ * - Missing files are ignored, other errors are logged and skipped
+ * - Records whose names are not single path elements are skipped, their
+ *   path could lie outside storage.base_dir
+ */
+func (s *LogService) removeFiles(logs []models.Log) {
+	for _, log := range logs {
+		if !isPathElement(log.ClientID) || !isPathElement(log.FileName) {
+			s.log.WithFields(logrus.Fields{
+				"client_id": log.ClientID,
+				"file_name": log.FileName,
+			}).Warn("Not removing log file with an unsafe name")
+			continue
+		}
+		path := s.StoragePath(log.ClientID, log.FileName)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			s.log.WithError(err).WithField("path", path).Warn("Failed to remove evicted log file")
+			continue
+		}
+		s.log.WithFields(logrus.Fields{
+			"client_id": log.ClientID,
+			"user_id":   log.UserID,
+			"file_name": log.FileName,
+		}).Info("Evicted log file")
+	}
 }
 
 /**
@@ -139,14 +514,18 @@ func (s *LogService) CreateLog(ctx context.Context, args *UploadLogArgs) (*model
  * - Database query errors
  */
 func (s *LogService) GetLogs(ctx context.Context, clientID, fname string) (string, error) {
-	if clientID == "" {
-		return "", &ValidationError{Field: "client_id", Message: "client_id is required"}
+	clientID, err := s.NormalizeClientID(clientID)
+	if err != nil {
+		return "", err
 	}
 	if fname == "" {
-		return "", &ValidationError{Field: "file_name", Message: "file_name is required"}
+		return "", &ValidationError{Field: "file_name", Message: internal.NewMessage(internal.MsgFieldRequired, "field", "file_name"), Rule: RuleRequired}
+	}
+	if !isPathElement(fname) {
+		return "", &ValidationError{Field: "file_name", Message: internal.NewMessage(internal.MsgFieldInvalid, "field", "file_name"), Rule: RuleFormat}
 	}
 
-	_, _, err := s.logDAO.ListLogs(ctx, clientID, "", fname, 1, 10)
+	_, _, err = s.logDAO.ListLogs(ctx, clientID, "", fname, 1, 10, dao.CountNone)
 	if err != nil {
 		s.log.WithError(err).WithFields(logrus.Fields{
 			"client_id": clientID,
@@ -155,18 +534,38 @@ func (s *LogService) GetLogs(ctx context.Context, clientID, fname string) (strin
 		return "", err
 	}
 
-	return filepath.Join("/data", clientID, fname), nil
+	return s.StoragePath(clientID, fname), nil
 }
 
-func (s *LogService) ListLogs(ctx context.Context, args *ListLogsArgs) (logs []models.Log, paging Paginated, err error) {
-	if args.Page < 1 {
-		args.Page = 1
+/**
+ * StoragePath computes where the file of a client is stored
+ * @param {string} clientID - Client identifier, must be a valid path element
+ * @param {string} fileName - Stored file name, must be a valid path element
+ * @returns {string} Absolute or base-relative file path
+ * @description
+ * - Files are stored as <storage.base_dir>/<client_id>/<file_name>
+ * - With storage.hash_subdirs, a directory named after the first two hex
+ *   digits of the file name's SHA-256 is inserted before the file name,
+ *   which keeps directories small and lookups deterministic
+ */
+func (s *LogService) StoragePath(clientID, fileName string) string {
+	cfg := internal.GetStorageConfig()
+	if !cfg.HashSubdirs {
+		return filepath.Join(cfg.BaseDir, clientID, fileName)
 	}
-	if args.PageSize < 1 || args.PageSize > 100 {
-		args.PageSize = 20
+	sum := sha256.Sum256([]byte(fileName))
+	return filepath.Join(cfg.BaseDir, clientID, hex.EncodeToString(sum[:1]), fileName)
+}
+
+func (s *LogService) ListLogs(ctx context.Context, args *ListLogsArgs) (logs []models.Log, paging Paginated, err error) {
+	normalizePage(pageResourceLog, &args.Page, &args.PageSize)
+	if args.ClientId != "" {
+		if args.ClientId, err = s.NormalizeClientID(args.ClientId); err != nil {
+			return
+		}
 	}
-	var total int64
-	logs, total, err = s.logDAO.ListLogs(ctx, args.ClientId, args.UserId, args.FileName, args.Page, args.PageSize)
+	var total *int64
+	logs, total, err = s.logDAO.ListLogs(ctx, args.ClientId, args.UserId, args.FileName, args.Page, args.PageSize, countMode(args.Count, args.ApproximateTotal))
 	if err != nil {
 		s.log.WithError(err).WithFields(logrus.Fields{
 			"page":      args.Page,
@@ -174,20 +573,140 @@ func (s *LogService) ListLogs(ctx context.Context, args *ListLogsArgs) (logs []m
 		}).Error("Failed to get logs by user")
 		return
 	}
-	paging.Page = int64(args.Page)
-	paging.PageSize = int64(args.PageSize)
-	paging.Total = total
-	paging.TotalPages = (total + int64(args.PageSize) - 1) / int64(args.PageSize)
+	if total != nil {
+		paging = NewPaginated(args.Page, args.PageSize, *total)
+		paging.Approximate = args.ApproximateTotal
+	} else {
+		paging = NewUncountedPaginated(args.Page, args.PageSize, len(logs))
+		if len(logs) > args.PageSize {
+			logs = logs[:args.PageSize]
+		}
+	}
 
 	s.log.WithFields(logrus.Fields{
 		"user_id":   args.UserId,
 		"page":      args.Page,
 		"page_size": args.PageSize,
-		"total":     total,
+		"counted":   total != nil,
 	}).Info("Logs retrieved successfully by user")
 	return
 }
 
+/**
+ * GetClientSummary summarizes the logs uploaded by a client
+ * @param {context.Context} ctx - Context for request cancellation
+ * @param {string} clientID - Client identifier
+ * @returns {*models.ClientLogSummary, error} Summary and error if any
+ * @description
+ * - Returns zero totals for a client without logs
+ * @throws
+ * - Validation errors for missing client ID
+ * - Database query errors
+ */
+func (s *LogService) GetClientSummary(ctx context.Context, clientID string) (*models.ClientLogSummary, error) {
+	clientID, err := s.NormalizeClientID(clientID)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := s.logDAO.SummarizeClient(ctx, clientID)
+	if err != nil {
+		s.log.WithError(err).WithField("client_id", clientID).Error("Failed to get client summary")
+		return nil, err
+	}
+	return summary, nil
+}
+
+/**
+ * GetLogStats computes log statistics for a date range
+ * @param {context.Context} ctx - Context for request cancellation
+ * @param {*LogStatsArgs} args - Date range, both bounds optional
+ * @returns {*LogStats, error} Statistics and error if any
+ * @description
+ * - EndDate defaults to today, StartDate to 7 days up to EndDate
+ * - Logs are attributed to the day of their last update
+ * @throws
+ * - Validation errors for malformed or reversed dates
+ * - Database query errors
+ */
+func (s *LogService) GetLogStats(ctx context.Context, args *LogStatsArgs) (*LogStats, error) {
+	end := internal.Now().Truncate(24 * time.Hour)
+	if args.EndDate != "" {
+		parsed, err := time.Parse("2006-01-02", args.EndDate)
+		if err != nil {
+			return nil, &ValidationError{Field: "end_date", Message: internal.NewMessage(internal.MsgFieldDateFormat, "field", "end_date"), Rule: RuleFormat}
+		}
+		end = parsed
+	}
+	start := end.AddDate(0, 0, -(defaultStatsDays - 1))
+	if args.StartDate != "" {
+		parsed, err := time.Parse("2006-01-02", args.StartDate)
+		if err != nil {
+			return nil, &ValidationError{Field: "start_date", Message: internal.NewMessage(internal.MsgFieldDateFormat, "field", "start_date"), Rule: RuleFormat}
+		}
+		start = parsed
+	}
+	if start.After(end) {
+		return nil, &ValidationError{Field: "start_date", Message: internal.NewMessage(internal.MsgDateRangeInverted), Rule: RuleRange}
+	}
+
+	to := end.AddDate(0, 0, 1)
+	daily, err := s.logDAO.CountByDay(ctx, start, to)
+	if err != nil {
+		s.log.WithError(err).Error("Failed to get daily log stats")
+		return nil, err
+	}
+	clients, err := s.logDAO.CountByClient(ctx, start, to)
+	if err != nil {
+		s.log.WithError(err).Error("Failed to get client log stats")
+		return nil, err
+	}
+
+	stats := &LogStats{
+		StartDate:     start.Format("2006-01-02"),
+		EndDate:       end.Format("2006-01-02"),
+		ActiveClients: int64(len(clients)),
+		Daily:         daily,
+		Clients:       clients,
+	}
+	for _, day := range daily {
+		stats.TotalLogs += day.Count
+	}
+	if stats.Daily == nil {
+		stats.Daily = []models.DailyLogCount{}
+	}
+	if stats.Clients == nil {
+		stats.Clients = []models.ClientLogCount{}
+	}
+	return stats, nil
+}
+
+/**
+ * ListModules lists the modules logs were uploaded for
+ * @param {context.Context} ctx - Context for request cancellation
+ * @param {string} clientID - Client identifier filter (optional)
+ * @returns {[]models.ModuleLogCount, error} Modules with their number of logs and error if any
+ * @throws
+ * - Validation errors for malformed client IDs
+ * - Database query errors
+ */
+func (s *LogService) ListModules(ctx context.Context, clientID string) ([]models.ModuleLogCount, error) {
+	if clientID != "" {
+		var err error
+		if clientID, err = s.NormalizeClientID(clientID); err != nil {
+			return nil, err
+		}
+	}
+	modules, err := s.logDAO.ListModules(ctx, clientID)
+	if err != nil {
+		s.log.WithError(err).WithField("client_id", clientID).Error("Failed to list log modules")
+		return nil, err
+	}
+	if modules == nil {
+		modules = []models.ModuleLogCount{}
+	}
+	return modules, nil
+}
+
 /**
  * DeleteOldLogs deletes logs older than specified date
  * @param {context.Context} ctx - Context for request cancellation
@@ -196,6 +715,8 @@ func (s *LogService) ListLogs(ctx context.Context, args *ListLogsArgs) (logs []m
  * @description
  * - Validates date parameter
  * - Performs cleanup of old log records
+ * - Records the deletion in the audit trail within the same transaction
+ * - Removes the files of the deleted records after the outermost commit
  * - Returns count of deleted records
  * @throws
  * - Validation errors for invalid date
@@ -204,15 +725,27 @@ func (s *LogService) ListLogs(ctx context.Context, args *ListLogsArgs) (logs []m
 func (s *LogService) DeleteOldLogs(ctx context.Context, beforeDate string) (int64, error) {
 	// Validate date parameter
 	if beforeDate == "" {
-		return 0, &ValidationError{Field: "before_date", Message: "before_date is required"}
+		return 0, &ValidationError{Field: "before_date", Message: internal.NewMessage(internal.MsgFieldRequired, "field", "before_date"), Rule: RuleRequired}
 	}
 
-	// Delete old logs
-	count, err := s.logDAO.DeleteOldLogs(ctx, beforeDate)
+	// Delete old logs and audit the purge atomically
+	var deleted []models.Log
+	err := s.logDAO.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		deleted, err = s.logDAO.DeleteOldLogs(ctx, beforeDate)
+		if err != nil {
+			return err
+		}
+		return s.auditService.Record(ctx, AuditActorSystem, AuditActionDelete, auditResourceLog, "",
+			map[string]interface{}{"before_date": beforeDate},
+			map[string]interface{}{"deleted_count": len(deleted)})
+	})
 	if err != nil {
 		s.log.WithError(err).WithField("before_date", beforeDate).Error("Failed to delete old logs")
 		return 0, err
 	}
+	internal.AfterCommit(ctx, func() { s.removeFiles(deleted) })
+	count := int64(len(deleted))
 
 	s.log.WithFields(logrus.Fields{
 		"before_date":   beforeDate,
@@ -223,26 +756,315 @@ func (s *LogService) DeleteOldLogs(ctx context.Context, beforeDate string) (int6
 }
 
 /**
- * validateAndExtractLog validates and extracts log data
- * @param {map[string]interface{}} data - Log data
- * @returns {*models.Log, error} Validated log and error if any
+ * ValidateUpload validates the arguments of a log upload
+ * @param {*UploadLogArgs} args - Upload arguments, ClientID is normalized in place
+ * @returns {error} ValidationErrors listing every invalid field, nil if valid
  * @description
  * - Validates required log fields
- * - Extracts log data
- * - Creates log object
+ * - Normalizes the client ID
+ * - The file name must be a single path element with an allowed extension,
+ *   the uploaded file is stored under it
+ * - Checks all fields before returning
  * @throws
- * - Validation errors for missing required fields
+ * - Validation errors for missing or malformed fields
  */
-func (s *LogService) validate(args *UploadLogArgs) error {
-	if args.ClientID == "" {
-		return &ValidationError{Field: "client_id", Message: "client_id is required and must be a string"}
+func (s *LogService) ValidateUpload(args *UploadLogArgs) error {
+	var errs ValidationErrors
+	if strings.TrimSpace(args.ClientID) == "" {
+		errs = append(errs, &ValidationError{Field: "client_id", Message: internal.NewMessage(internal.MsgFieldRequiredString, "field", "client_id"), Rule: RuleRequired})
+	} else if clientID, err := s.NormalizeClientID(args.ClientID); err != nil {
+		if err := errs.Add(err); err != nil {
+			return err
+		}
+	} else {
+		args.ClientID = clientID
 	}
 	if args.UserID == "" {
-		return &ValidationError{Field: "user_id", Message: "user_id is required and must be a string"}
+		errs = append(errs, &ValidationError{Field: "user_id", Message: internal.NewMessage(internal.MsgFieldRequiredString, "field", "user_id"), Rule: RuleRequired})
 	}
 	if args.FileName == "" {
-		return &ValidationError{Field: "file_name", Message: "file_name is required and must be a string"}
+		errs = append(errs, &ValidationError{Field: "file_name", Message: internal.NewMessage(internal.MsgFieldRequiredString, "field", "file_name"), Rule: RuleRequired})
+	} else if err := checkFileName(args.FileName); err != nil {
+		errs = append(errs, err)
+	}
+	return errs.Err()
+}
+
+/**
+ * HashContent computes the content hash of an uploaded file
+ * @param {io.ReadSeeker} file - Uploaded file, rewound afterwards
+ * @returns {string, error} Hex encoded SHA-256 and error if the file cannot be read
+ */
+func (s *LogService) HashContent(file io.ReadSeeker) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
 	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// gzipMagic is the header every gzip stream starts with
+var gzipMagic = []byte{0x1f, 0x8b}
+
+/**
+ * CountLines counts the lines of an uploaded file
+ * @param {io.ReadSeeker} file - Uploaded file, rewound afterwards
+ * @param {string} name - Uploaded file name
+ * @returns {int64, bool, error} Number of lines, whether the file is gzip compressed, and error if any
+ * @description
+ * - Gzip is detected by its magic bytes or a .gz extension
+ * - Compressed files are only counted when uploads.gzip.decompress is set,
+ *   the stored file stays compressed either way
+ * - Decompression stops at uploads.gzip.max_decompressed_bytes to defuse gzip bombs
+ * - A last line without a trailing newline is counted
+ * @throws
+ * - Validation errors for corrupt gzip streams and oversized decompressed content
+ * - File read errors
+ */
+func (s *LogService) CountLines(file io.ReadSeeker, name string) (int64, bool, error) {
+	head := make([]byte, len(gzipMagic))
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return 0, false, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, false, err
+	}
+	compressed := bytes.Equal(head[:n], gzipMagic) || strings.EqualFold(filepath.Ext(name), ".gz")
 
+	var r io.Reader = file
+	limit := int64(-1)
+	if compressed {
+		cfg := internal.GetUploadGzipConfig()
+		if !cfg.Decompress {
+			return 0, true, nil
+		}
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return 0, true, &ValidationError{Field: "logfile", Message: internal.NewMessage(internal.MsgLogfileGzipInvalid), Rule: RuleFormat}
+		}
+		defer gz.Close()
+		limit = cfg.MaxDecompressedBytes
+		r = io.LimitReader(gz, limit+1)
+	}
+
+	var lines, total int64
+	last := byte('\n')
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			total += int64(n)
+			lines += int64(bytes.Count(buf[:n], []byte{'\n'}))
+			last = buf[n-1]
+		}
+		// Checked before EOF, a read may return the last bytes together with io.EOF
+		if limit >= 0 && total > limit {
+			return 0, true, &ValidationError{Field: "logfile", Message: internal.NewMessage(internal.MsgLogfileTooLarge), Rule: RuleMaxSize}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if compressed {
+				return 0, true, &ValidationError{Field: "logfile", Message: internal.NewMessage(internal.MsgLogfileGzipInvalid), Rule: RuleFormat}
+			}
+			return 0, false, err
+		}
+	}
+	if last != '\n' {
+		lines++
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, compressed, err
+	}
+	return lines, compressed, nil
+}
+
+/**
+ * findDuplicate looks for a stored log of the user with the same content
+ * @param {context.Context} ctx - Context for request cancellation
+ * @param {*UploadLogArgs} args - Validated upload arguments, nothing is matched without ContentHash
+ * @returns {*models.Log, error} Existing log, nil if the file must be written, and error if any
+ * @description
+ * - Prefers the record of the requested client and file, its file needs no new name
+ * - Only matches records whose file is still present in storage
+ * @throws
+ * - Database query errors
+ */
+func (s *LogService) findDuplicate(ctx context.Context, args *UploadLogArgs) (*models.Log, error) {
+	if args.ContentHash == "" {
+		return nil, nil
+	}
+	existing, err := s.logDAO.GetLog(ctx, args.ClientID, args.FileName)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil || existing.UserID != args.UserID || existing.ContentHash != args.ContentHash {
+		if existing, err = s.logDAO.FindByContentHash(ctx, args.UserID, args.ContentHash); err != nil || existing == nil {
+			return nil, err
+		}
+	}
+	if _, err := os.Stat(s.StoragePath(existing.ClientID, existing.FileName)); err != nil {
+		return nil, nil
+	}
+	return existing, nil
+}
+
+/**
+ * CheckStorage verifies the storage base directory is still usable
+ * @returns {error} StorageError describing why files cannot be stored
+ * @description
+ * - Cheap stat of storage.base_dir, following symlinks, run before each upload
+ * - The full writability check runs once at startup
+ */
+func (s *LogService) CheckStorage() error {
+	if err := internal.CheckDirUsable(internal.GetStorageConfig().BaseDir); err != nil {
+		s.log.WithError(err).Error("Storage base directory is unusable")
+		return &StorageError{Message: internal.NewMessage(internal.MsgStorageUnavailable), Unavailable: true, Err: err}
+	}
 	return nil
 }
+
+/**
+ * LogETag returns the entity tag of a log record
+ * @param {*models.Log} log - Log record
+ * @returns {string} Quoted LastLineNo, e.g. "120"
+ * @description
+ * - Clients send it back in If-Match to append only on top of what they have seen
+ */
+func LogETag(log *models.Log) string {
+	return strconv.Quote(strconv.FormatInt(log.LastLineNo, 10))
+}
+
+/**
+ * ParseIfMatch parses an If-Match header carrying a log entity tag
+ * @param {string} header - If-Match header value, empty if absent
+ * @returns {*int64, bool, error} Expected LastLineNo, nil if the header is absent or "*",
+ *   true for "*", and error if any
+ * @description
+ * - "*" matches any existing log
+ * - Weak tags never match, If-Match uses the strong comparison
+ * @throws
+ * - PreconditionFailedError for a weak entity tag
+ * - Validation error when the header is not "*" or a single quoted line number
+ */
+func ParseIfMatch(header string) (*int64, bool, error) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return nil, false, nil
+	}
+	if header == "*" {
+		return nil, true, nil
+	}
+	if strings.HasPrefix(header, `W/"`) {
+		return nil, false, logChanged(nil)
+	}
+	invalid := &ValidationError{Field: "If-Match", Message: internal.NewMessage(internal.MsgFieldInvalid, "field", "If-Match"), Rule: RuleFormat}
+	unquoted, err := strconv.Unquote(header)
+	if err != nil || !strings.HasPrefix(header, `"`) {
+		return nil, false, invalid
+	}
+	lastLineNo, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || lastLineNo < 0 {
+		return nil, false, invalid
+	}
+	return &lastLineNo, false, nil
+}
+
+/**
+ * logChanged builds the error of a stale If-Match
+ * @param {*models.Log} current - Current log record, nil if it does not exist
+ * @returns {error} PreconditionFailedError carrying the current entity tag
+ */
+func logChanged(current *models.Log) error {
+	err := &PreconditionFailedError{Message: internal.NewMessage(internal.MsgLogChanged)}
+	if current != nil {
+		err.ETag = LogETag(current)
+	}
+	return err
+}
+
+/**
+ * NormalizeClientID brings a client ID into its canonical form and validates it
+ * @param {string} clientID - Client ID sent by the client
+ * @returns {string, error} Canonical client ID and error if rejected
+ * @description
+ * - Trims surrounding whitespace
+ * - Lower-cases the ID when client_id.lowercase is set
+ * - The result must match client_id.pattern and be a single path element,
+ *   which also bounds the cardinality of the client_id metrics label
+ * @throws
+ * - Validation errors for empty and malformed IDs
+ */
+func (s *LogService) NormalizeClientID(clientID string) (string, error) {
+	cfg := internal.GetClientIDConfig()
+	clientID = strings.TrimSpace(clientID)
+	if cfg.Lowercase {
+		clientID = strings.ToLower(clientID)
+	}
+	if clientID == "" {
+		return "", &ValidationError{Field: "client_id", Message: internal.NewMessage(internal.MsgFieldRequired, "field", "client_id"), Rule: RuleRequired}
+	}
+	if !cfg.Pattern.MatchString(clientID) || !isPathElement(clientID) {
+		return "", &ValidationError{Field: "client_id", Message: internal.NewMessage(internal.MsgFieldInvalid, "field", "client_id"), Rule: RuleFormat}
+	}
+	return clientID, nil
+}
+
+/**
+ * checkFileName validates the file name of a log upload
+ * @param {string} name - File name sent by the client
+ * @returns {*ValidationError} nil if the name can be stored
+ * @description
+ * - Rejects names that are not a single path element, they would escape the client directory
+ * - Rejects extensions missing from uploads.allowed_extensions
+ */
+func checkFileName(name string) *ValidationError {
+	if !isPathElement(name) {
+		return &ValidationError{Field: "file_name", Message: internal.NewMessage(internal.MsgFieldInvalid, "field", "file_name"), Rule: RuleFormat}
+	}
+	allowed := internal.GetUploadAllowedExtensions()
+	if len(allowed) == 0 {
+		return nil
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, a := range allowed {
+		if ext == a {
+			return nil
+		}
+	}
+	return &ValidationError{
+		Field:   "file_name",
+		Message: internal.NewMessage(internal.MsgFileExtensionNotAllowed, "allowed", strings.Join(allowed, ", ")),
+		Rule:    RuleEnum,
+	}
+}
+
+/**
+ * sanitizeFileName reduces a file name to its last path element
+ * @param {string} name - File name, possibly containing a path
+ * @returns {string, error} Base name and error if it is empty or a relative directory reference
+ * @throws
+ * - Validation errors for unsafe names
+ */
+func sanitizeFileName(name string) (string, error) {
+	base := filepath.Base(filepath.Clean("/" + name))
+	if base == "/" || base == "." || base == ".." || strings.ContainsRune(base, 0) {
+		return "", &ValidationError{Field: "logfile", Message: internal.NewMessage(internal.MsgLogfileNameInvalid), Rule: RuleFormat}
+	}
+	return base, nil
+}
+
+/**
+ * isPathElement reports whether a name can be used as a single storage path element
+ * @param {string} name - Client ID or file name
+ * @returns {bool} False for names containing separators or referring to a directory
+ */
+func isPathElement(name string) bool {
+	clean, err := sanitizeFileName(name)
+	return err == nil && clean == name && !strings.ContainsRune(name, '\\')
+}
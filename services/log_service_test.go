@@ -0,0 +1,526 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/zgsm-ai/client-manager/internal"
+	"github.com/zgsm-ai/client-manager/internal/testutil"
+	"github.com/zgsm-ai/client-manager/models"
+)
+
+func TestCreateLogEvictsLeastRecentFiles(t *testing.T) {
+	s, db := newTestLogService(t)
+	testutil.SetConfig(t, "log.max_files_per_user", 2)
+
+	first := uploadTestLog(t, s, UploadLogArgs{ClientID: "c1", UserID: "u1", FileName: "a.log"})
+	uploadTestLog(t, s, UploadLogArgs{ClientID: "c1", UserID: "u1", FileName: "b.log"})
+	uploadTestLog(t, s, UploadLogArgs{ClientID: "c1", UserID: "u2", FileName: "other.log"})
+	uploadTestLog(t, s, UploadLogArgs{ClientID: "c1", UserID: "u1", FileName: "c.log"})
+
+	var count int64
+	db.Model(&models.Log{}).Where("id = ?", first.ID).Count(&count)
+	if count != 0 {
+		t.Errorf("least recent log of u1 was not evicted")
+	}
+	tests := []struct {
+		file string
+		kept bool
+	}{
+		{"a.log", false},
+		{"b.log", true},
+		{"c.log", true},
+		{"other.log", true},
+	}
+	for _, tt := range tests {
+		if got := fileExists(s.StoragePath("c1", tt.file)); got != tt.kept {
+			t.Errorf("file %s exists = %v, want %v", tt.file, got, tt.kept)
+		}
+	}
+}
+
+func TestCreateLogUpdateDoesNotEvict(t *testing.T) {
+	s, _ := newTestLogService(t)
+	testutil.SetConfig(t, "log.max_files_per_user", 1)
+
+	uploadTestLog(t, s, UploadLogArgs{ClientID: "c1", UserID: "u1", FileName: "a.log", LastLineNo: 10})
+	uploadTestLog(t, s, UploadLogArgs{ClientID: "c1", UserID: "u1", FileName: "a.log", LastLineNo: 20})
+
+	if !fileExists(s.StoragePath("c1", "a.log")) {
+		t.Errorf("appending to an existing log evicted it")
+	}
+}
+
+func TestRemoveFilesSkipsUnsafeNames(t *testing.T) {
+	s, _ := newTestLogService(t)
+	outside := uploadTestLog(t, s, UploadLogArgs{ClientID: "c1", UserID: "u1", FileName: "victim.log"})
+
+	// A legacy record pointing from client c2 to the file of c1
+	s.removeFiles([]models.Log{{ClientID: "c2", FileName: "../c1/victim.log"}})
+
+	if !fileExists(s.StoragePath(outside.ClientID, outside.FileName)) {
+		t.Errorf("removeFiles deleted a file outside the record's client directory")
+	}
+}
+
+func TestValidateUploadFileName(t *testing.T) {
+	s, _ := newTestLogService(t)
+	tests := []struct {
+		name     string
+		fileName string
+		rule     string
+	}{
+		{"plain", "app.log", ""},
+		{"empty", "", RuleRequired},
+		{"parent traversal", "../../../etc/x.log", RuleFormat},
+		{"subdirectory", "sub/app.log", RuleFormat},
+		{"backslash", `sub\app.log`, RuleFormat},
+		{"dot dot", "..", RuleFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := UploadLogArgs{ClientID: "c1", UserID: "u1", FileName: tt.fileName}
+			assertRule(t, s.ValidateUpload(&args), "file_name", tt.rule)
+		})
+	}
+}
+
+func TestCreateLogRejectsUnsafeFileName(t *testing.T) {
+	s, db := newTestLogService(t)
+	args := UploadLogArgs{ClientID: "c1", UserID: "u1", FileName: "../../../etc/x.log"}
+	if _, _, err := s.CreateLog(context.Background(), &args); err == nil {
+		t.Fatal("CreateLog accepted a file name escaping the storage directory")
+	}
+	var count int64
+	db.Model(&models.Log{}).Count(&count)
+	if count != 0 {
+		t.Errorf("stored %d records for a rejected upload", count)
+	}
+}
+
+// assertRule checks that err reports rule for field, or no error for field when rule is empty
+func assertRule(t *testing.T, err error, field, rule string) {
+	t.Helper()
+	var got *ValidationError
+	var all ValidationErrors
+	if errors.As(err, &all) {
+		for _, e := range all {
+			if e.Field == field {
+				got = e
+			}
+		}
+	} else if !errors.As(err, &got) || got.Field != field {
+		got = nil
+	}
+	switch {
+	case rule == "" && got != nil:
+		t.Errorf("unexpected %s error: %v", field, got)
+	case rule != "" && got == nil:
+		t.Errorf("expected %s error with rule %q, got %v", field, rule, err)
+	case rule != "" && got.Rule != rule:
+		t.Errorf("%s error rule = %q, want %q", field, got.Rule, rule)
+	}
+}
+
+func TestValidateUploadExtensions(t *testing.T) {
+	s, _ := newTestLogService(t)
+	tests := []struct {
+		name     string
+		allowed  []string
+		fileName string
+		rule     string
+	}{
+		{"allowed", []string{".log", ".txt"}, "app.log", ""},
+		{"allowed case-insensitive", []string{".log"}, "APP.LOG", ""},
+		{"configured without dot", []string{"txt"}, "app.txt", ""},
+		{"compressed", []string{".log", ".gz"}, "app.log.gz", ""},
+		{"disallowed", []string{".log", ".txt"}, "app.exe", RuleEnum},
+		{"no extension", []string{".log"}, "app", RuleEnum},
+		{"double extension", []string{".log"}, "app.log.exe", RuleEnum},
+		{"empty allowlist allows all", nil, "app.exe", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.SetConfig(t, "uploads.allowed_extensions", tt.allowed)
+			args := UploadLogArgs{ClientID: "c1", UserID: "u1", FileName: tt.fileName}
+			assertRule(t, s.ValidateUpload(&args), "file_name", tt.rule)
+		})
+	}
+}
+
+func TestCreateLogInRequestTransaction(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		kept   bool
+	}{
+		{"committed", http.StatusCreated, false},
+		{"rolled back", http.StatusInternalServerError, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, db := newTestLogService(t)
+			testutil.SetConfig(t, "log.max_files_per_user", 1)
+			uploadTestLog(t, s, UploadLogArgs{ClientID: "c1", UserID: "u1", FileName: "a.log"})
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.POST("/logs", internal.TxMiddleware(db, testutil.NewLogger()), func(c *gin.Context) {
+				args := UploadLogArgs{ClientID: "c1", UserID: "u1", FileName: "b.log"}
+				if _, err := s.SaveLog(c.Request.Context(), &args, strings.NewReader("line\n")); err != nil {
+					t.Errorf("save log: %v", err)
+				}
+				// A later step of the request failing after the log was saved
+				c.JSON(tt.status, gin.H{})
+			})
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/logs", nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			var count int64
+			db.Model(&models.Log{}).Where("file_name = ?", "a.log").Count(&count)
+			if got := count == 1; got != tt.kept {
+				t.Errorf("evicted record kept = %v, want %v", got, tt.kept)
+			}
+			if got := fileExists(s.StoragePath("c1", "a.log")); got != tt.kept {
+				t.Errorf("evicted file kept = %v, want %v", got, tt.kept)
+			}
+			if got := fileExists(s.StoragePath("c1", "b.log")); got == tt.kept {
+				t.Errorf("new file published = %v, want %v", got, !tt.kept)
+			}
+			if left := stagedFiles(t, filepath.Dir(s.StoragePath("c1", "b.log"))); len(left) != 0 {
+				t.Errorf("staged files left behind: %v", left)
+			}
+		})
+	}
+}
+
+func TestSaveLogDuplicateWithIfMatch(t *testing.T) {
+	matching, stale := int64(10), int64(5)
+	tests := []struct {
+		name               string
+		ifMatch            *int64
+		preconditionFailed bool
+	}{
+		{"unconditional", nil, false},
+		{"matching If-Match", &matching, false},
+		{"stale If-Match", &stale, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestLogService(t)
+			base := UploadLogArgs{ClientID: "c1", UserID: "u1", FileName: "a.log", LastLineNo: 10, ContentHash: "h1"}
+			uploadTestLog(t, s, base)
+
+			// Identical content still has its If-Match evaluated
+			args := base
+			args.IfMatch = tt.ifMatch
+			saved, err := s.SaveLog(context.Background(), &args, strings.NewReader("line\n"))
+			var precondErr *PreconditionFailedError
+			if got := errors.As(err, &precondErr); got != tt.preconditionFailed {
+				t.Fatalf("save log error = %v, want precondition failed %v", err, tt.preconditionFailed)
+			}
+			if err == nil && !saved.Duplicate {
+				t.Errorf("duplicate = false, want true")
+			}
+		})
+	}
+}
+
+func TestNormalizeClientID(t *testing.T) {
+	s, _ := newTestLogService(t)
+	tests := []struct {
+		name      string
+		clientID  string
+		lowercase bool
+		want      string
+		rule      string
+	}{
+		{"unchanged", "client-1", false, "client-1", ""},
+		{"trimmed", "  client-1\t", false, "client-1", ""},
+		{"case kept", "Client-1", false, "Client-1", ""},
+		{"lowercased", "Client-1", true, "client-1", ""},
+		{"empty", "   ", false, "", RuleRequired},
+		{"disallowed character", "client$1", false, "", RuleFormat},
+		{"path element", "..", false, "", RuleFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.SetConfig(t, "client_id.lowercase", tt.lowercase)
+			got, err := s.NormalizeClientID(tt.clientID)
+			if tt.rule != "" {
+				assertRule(t, err, "client_id", tt.rule)
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("NormalizeClientID(%q) = %q, %v, want %q", tt.clientID, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetLogStatsJSON(t *testing.T) {
+	s, db := newTestLogService(t)
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 12, 0, 0, 0, time.UTC) }
+	seeded := []models.Log{
+		{ClientID: "c1", FileName: "a.log", SizeBytes: 10, UpdatedAt: day(1)},
+		{ClientID: "c1", FileName: "b.log", SizeBytes: 20, UpdatedAt: day(2)},
+		{ClientID: "c2", FileName: "a.log", SizeBytes: 5, UpdatedAt: day(2)},
+		{ClientID: "c3", FileName: "old.log", SizeBytes: 1, UpdatedAt: day(1).AddDate(0, -1, 0)},
+	}
+	if err := db.Create(&seeded).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := s.GetLogStats(context.Background(), &LogStatsArgs{StartDate: "2024-03-01", EndDate: "2024-03-07"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"start_date", `"2024-03-01"`},
+		{"end_date", `"2024-03-07"`},
+		{"total_logs", `3`},
+		{"active_clients", `2`},
+		{"daily", `[{"count":1,"date":"2024-03-01"},{"count":2,"date":"2024-03-02"}]`},
+		{"clients", `[{"bytes":30,"client_id":"c1","files":2},{"bytes":5,"client_id":"c2","files":1}]`},
+	}
+	if len(got) != len(tests) {
+		t.Errorf("stats has keys %v, want %d keys", got, len(tests))
+	}
+	for _, tt := range tests {
+		value, ok := got[tt.key]
+		if !ok {
+			t.Errorf("stats has no %q key", tt.key)
+			continue
+		}
+		encoded, _ := json.Marshal(value)
+		if string(encoded) != tt.want {
+			t.Errorf("%s = %s, want %s", tt.key, encoded, tt.want)
+		}
+	}
+}
+
+func TestGetLogStatsValidation(t *testing.T) {
+	s, _ := newTestLogService(t)
+	tests := []struct {
+		name  string
+		args  LogStatsArgs
+		field string
+		rule  string
+	}{
+		{"malformed end date", LogStatsArgs{EndDate: "03/07/2024"}, "end_date", RuleFormat},
+		{"malformed start date", LogStatsArgs{StartDate: "yesterday"}, "start_date", RuleFormat},
+		{"reversed range", LogStatsArgs{StartDate: "2024-03-07", EndDate: "2024-03-01"}, "start_date", RuleRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.GetLogStats(context.Background(), &tt.args)
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("error = %v, want a ValidationError", err)
+			}
+			if validationErr.Field != tt.field || validationErr.Rule != tt.rule {
+				t.Errorf("error on %s (%s), want %s (%s)", validationErr.Field, validationErr.Rule, tt.field, tt.rule)
+			}
+		})
+	}
+}
+
+func TestStoragePath(t *testing.T) {
+	s, _ := newTestLogService(t)
+	testutil.SetConfig(t, "storage.base_dir", "/srv/logs")
+	tests := []struct {
+		name        string
+		hashSubdirs bool
+		fileName    string
+		want        string
+	}{
+		{"flat", false, "a.log", "/srv/logs/c1/a.log"},
+		{"hashed", true, "a.log", "/srv/logs/c1/e4/a.log"},
+		{"hashed other file", true, "app.log", "/srv/logs/c1/eb/app.log"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.SetConfig(t, "storage.hash_subdirs", tt.hashSubdirs)
+			if got := s.StoragePath("c1", tt.fileName); got != filepath.FromSlash(tt.want) {
+				t.Errorf("StoragePath = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashContent(t *testing.T) {
+	s, _ := newTestLogService(t)
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{"line\n", "c73b73af8851e9e91bc6b4dc12e7dace0a2bfb931c1d0b8b36ef367319f58cd1"},
+	}
+	for _, tt := range tests {
+		file := strings.NewReader(tt.content)
+		got, err := s.HashContent(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("HashContent(%q) = %s, want %s", tt.content, got, tt.want)
+		}
+		if rest, _ := io.ReadAll(file); string(rest) != tt.content {
+			t.Errorf("file was not rewound, %q left to read", rest)
+		}
+	}
+}
+
+func TestCountLines(t *testing.T) {
+	s, _ := newTestLogService(t)
+	testutil.SetConfig(t, "uploads.gzip.max_decompressed_bytes", 16)
+	tests := []struct {
+		name           string
+		decompress     bool
+		fileName       string
+		content        string
+		wantLines      int64
+		wantCompressed bool
+		wantRule       string
+	}{
+		{"plain", true, "a.log", "one\ntwo\n", 2, false, ""},
+		{"plain without final newline", true, "a.log", "one\ntwo", 2, false, ""},
+		{"empty", true, "a.log", "", 0, false, ""},
+		{"gzip by magic", true, "a.log", string(gzipped(t, []byte("one\ntwo\nthree\n"))), 3, true, ""},
+		{"gzip by extension", true, "a.log.gz", string(gzipped(t, []byte("one\n"))), 1, true, ""},
+		{"gzip not decompressed", false, "a.log.gz", string(gzipped(t, []byte("one\ntwo\n"))), 0, true, ""},
+		{"corrupt gzip", true, "a.log.gz", "not gzip", 0, true, RuleFormat},
+		{"decompressed at limit", true, "a.log.gz", string(gzipped(t, []byte(strings.Repeat("x\n", 8)))), 8, true, ""},
+		{"decompressed one byte over limit", true, "a.log.gz", string(gzipped(t, []byte(strings.Repeat("x\n", 8)+"y"))), 0, true, RuleMaxSize},
+		{"decompression limit", true, "a.log.gz", string(gzipped(t, []byte(strings.Repeat("x\n", 100)))), 0, true, RuleMaxSize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.SetConfig(t, "uploads.gzip.decompress", tt.decompress)
+			file := strings.NewReader(tt.content)
+			lines, compressed, err := s.CountLines(file, tt.fileName)
+			var validationErr *ValidationError
+			if tt.wantRule != "" {
+				if !errors.As(err, &validationErr) || validationErr.Field != "logfile" || validationErr.Rule != tt.wantRule {
+					t.Fatalf("error = %v, want a logfile ValidationError with rule %q", err, tt.wantRule)
+				}
+			} else if err != nil {
+				t.Fatalf("CountLines: %v", err)
+			}
+			if lines != tt.wantLines || compressed != tt.wantCompressed {
+				t.Errorf("CountLines = %d lines, compressed %v, want %d, %v", lines, compressed, tt.wantLines, tt.wantCompressed)
+			}
+		})
+	}
+}
+
+func TestSaveLogWritesBeforeCommitting(t *testing.T) {
+	s, db := newTestLogService(t)
+	testutil.SetConfig(t, "log.max_files_per_user", 1)
+	testutil.SetConfig(t, "storage.hash_subdirs", true)
+	kept := uploadTestLog(t, s, UploadLogArgs{ClientID: "c1", UserID: "u1", FileName: "a.log", LastLineNo: 1})
+	keptPath := s.StoragePath(kept.ClientID, kept.FileName)
+
+	// A file where the directory of b.log belongs makes its write fail
+	blockedDir := filepath.Dir(s.StoragePath("c1", "b.log"))
+	if blockedDir == filepath.Dir(keptPath) {
+		t.Fatal("a.log and b.log share a hashed subdirectory")
+	}
+	if err := os.WriteFile(blockedDir, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		args     UploadLogArgs
+		isErr    func(err error) bool
+		stageDir string
+	}{
+		{"write fails", UploadLogArgs{ClientID: "c1", UserID: "u1", FileName: "b.log", LastLineNo: 1},
+			func(err error) bool { var e *StorageError; return errors.As(err, &e) }, ""},
+		{"record rejected", UploadLogArgs{ClientID: "c1", UserID: "u1", FileName: "c.log", LastLineNo: 1, IfMatch: new(int64)},
+			func(err error) bool { var e *PreconditionFailedError; return errors.As(err, &e) },
+			filepath.Dir(s.StoragePath("c1", "c.log"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			_, err := s.SaveLog(context.Background(), &args, strings.NewReader("line\n"))
+			if !tt.isErr(err) {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			var count int64
+			db.Model(&models.Log{}).Where("file_name = ?", args.FileName).Count(&count)
+			if count != 0 {
+				t.Errorf("stored a record for the failed upload")
+			}
+			if fileExists(s.StoragePath(args.ClientID, args.FileName)) {
+				t.Errorf("the failed upload left a file behind")
+			}
+			if tt.stageDir != "" {
+				if left := stagedFiles(t, tt.stageDir); len(left) != 0 {
+					t.Errorf("staged files left behind: %v", left)
+				}
+			}
+			// The user's older log was not evicted for an upload that never landed
+			db.Model(&models.Log{}).Where("id = ?", kept.ID).Count(&count)
+			if count != 1 || !fileExists(keptPath) {
+				t.Errorf("a.log was evicted, record %d, file %v", count, fileExists(keptPath))
+			}
+		})
+	}
+}
+
+func TestSaveLogReplacesFile(t *testing.T) {
+	s, _ := newTestLogService(t)
+	ctx := context.Background()
+	for _, content := range []string{"first\n", "second\nthird\n"} {
+		args := UploadLogArgs{ClientID: "c1", UserID: "u1", FileName: "a.log"}
+		saved, err := s.SaveLog(ctx, &args, strings.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if saved.Written != int64(len(content)) {
+			t.Errorf("written = %d, want %d", saved.Written, len(content))
+		}
+	}
+	path := s.StoragePath("c1", "a.log")
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "second\nthird\n" {
+		t.Errorf("stored content = %q, want the second upload", got)
+	}
+	if left := stagedFiles(t, filepath.Dir(path)); len(left) != 0 {
+		t.Errorf("staged files left behind: %v", left)
+	}
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"github.com/zgsm-ai/client-manager/dao"
+	"github.com/zgsm-ai/client-manager/internal"
+)
+
+// Pagination resources, used as pagination.<resource> configuration keys
+const (
+	pageResourceLog   = "log"
+	pageResourceAudit = "audit"
+	// pageResourceSearch limits the hits per source of a search
+	pageResourceSearch = "search"
+)
+
+/**
+ * Paginated describes the pagination metadata returned by list endpoints
+ * @description
+ * - TotalPages is 0 when there are no records
+ * - Total and TotalPages are null when the list was requested with count=false
+ * - Approximate flags a total that may come from a cache and lag recent writes
+ * - OutOfRange flags a requested page beyond the last page, data is empty then
+ */
+type Paginated struct {
+	Page        int64  `json:"page"`
+	PageSize    int64  `json:"page_size"`
+	Total       *int64 `json:"total"`
+	TotalPages  *int64 `json:"total_pages"`
+	HasNext     bool   `json:"has_next"`
+	HasPrev     bool   `json:"has_prev"`
+	OutOfRange  bool   `json:"out_of_range"`
+	Approximate bool   `json:"approximate,omitempty"`
+}
+
+/**
+ * NewPaginated builds pagination metadata for a list result
+ * @param {int} page - Requested page number (1-based)
+ * @param {int} pageSize - Number of items per page
+ * @param {int64} total - Total number of matching records
+ * @returns {Paginated} Pagination metadata
+ * @description
+ * - Computes total pages, rounding up
+ * - Sets HasNext/HasPrev for navigation
+ * - Sets OutOfRange when page exceeds the last page (page 1 is always in range)
+ */
+func NewPaginated(page, pageSize int, total int64) Paginated {
+	var totalPages int64
+	if pageSize > 0 {
+		totalPages = (total + int64(pageSize) - 1) / int64(pageSize)
+	}
+	paging := Paginated{
+		Page:       int64(page),
+		PageSize:   int64(pageSize),
+		Total:      &total,
+		TotalPages: &totalPages,
+	}
+	lastPage := totalPages
+	if lastPage < 1 {
+		lastPage = 1
+	}
+	paging.HasNext = paging.Page < totalPages
+	paging.HasPrev = paging.Page > 1
+	paging.OutOfRange = paging.Page > lastPage
+	return paging
+}
+
+/**
+ * normalizePage applies the page size limits of a resource to list arguments
+ * @param {string} resource - Pagination resource, e.g. pageResourceLog
+ * @param {*int} page - Requested page, raised to 1 when smaller
+ * @param {*int} pageSize - Requested page size, the resource default when unset, capped at its maximum
+ */
+func normalizePage(resource string, page, pageSize *int) {
+	if *page < 1 {
+		*page = 1
+	}
+	defaultSize, maxSize := internal.GetPageSizeLimits(resource)
+	switch {
+	case *pageSize < 1:
+		*pageSize = defaultSize
+	case *pageSize > maxSize:
+		*pageSize = maxSize
+	}
+}
+
+/**
+ * countMode maps the count flags of list arguments to a DAO count mode
+ * @param {bool} count - Whether the total was requested
+ * @param {bool} approximate - Whether a cached total is acceptable
+ * @returns {dao.CountMode} Count mode for the DAO
+ */
+func countMode(count, approximate bool) dao.CountMode {
+	switch {
+	case !count:
+		return dao.CountNone
+	case approximate:
+		return dao.CountApproximate
+	default:
+		return dao.CountExact
+	}
+}
+
+/**
+ * NewUncountedPaginated builds pagination metadata for a list fetched without counting
+ * @param {int} page - Requested page number (1-based)
+ * @param {int} pageSize - Number of items per page
+ * @param {int} fetched - Number of rows fetched, the DAO fetches up to pageSize+1
+ * @returns {Paginated} Pagination metadata with Total and TotalPages left null
+ * @description
+ * - HasNext is set when the extra row was found
+ * - OutOfRange is set when a page after the first returned no rows
+ */
+func NewUncountedPaginated(page, pageSize, fetched int) Paginated {
+	return Paginated{
+		Page:       int64(page),
+		PageSize:   int64(pageSize),
+		HasNext:    fetched > pageSize,
+		HasPrev:    page > 1,
+		OutOfRange: page > 1 && fetched == 0,
+	}
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/zgsm-ai/client-manager/internal/testutil"
+)
+
+func TestNewPaginated(t *testing.T) {
+	tests := []struct {
+		name                         string
+		page, pageSize               int
+		total, totalPages            int64
+		hasNext, hasPrev, outOfRange bool
+	}{
+		{"empty", 1, 10, 0, 0, false, false, false},
+		{"empty beyond first page", 2, 10, 0, 0, false, true, true},
+		{"first of several", 1, 10, 25, 3, true, false, false},
+		{"middle", 2, 10, 25, 3, true, true, false},
+		{"last", 3, 10, 25, 3, false, true, false},
+		{"exact multiple", 2, 10, 20, 2, false, true, false},
+		{"beyond last", 4, 10, 25, 3, false, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewPaginated(tt.page, tt.pageSize, tt.total)
+			if *got.Total != tt.total || *got.TotalPages != tt.totalPages {
+				t.Errorf("total = %d, total_pages = %d, want %d and %d", *got.Total, *got.TotalPages, tt.total, tt.totalPages)
+			}
+			if got.HasNext != tt.hasNext || got.HasPrev != tt.hasPrev || got.OutOfRange != tt.outOfRange {
+				t.Errorf("has_next = %v, has_prev = %v, out_of_range = %v, want %v, %v, %v",
+					got.HasNext, got.HasPrev, got.OutOfRange, tt.hasNext, tt.hasPrev, tt.outOfRange)
+			}
+		})
+	}
+}
+
+func TestNewUncountedPaginated(t *testing.T) {
+	tests := []struct {
+		name                         string
+		page, fetched                int
+		hasNext, hasPrev, outOfRange bool
+	}{
+		{"empty first page", 1, 0, false, false, false},
+		{"extra row found", 1, 11, true, false, false},
+		{"last page", 2, 4, false, true, false},
+		{"beyond last page", 3, 0, false, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewUncountedPaginated(tt.page, 10, tt.fetched)
+			if got.Total != nil || got.TotalPages != nil {
+				t.Errorf("total = %v, total_pages = %v, want both nil", got.Total, got.TotalPages)
+			}
+			if got.HasNext != tt.hasNext || got.HasPrev != tt.hasPrev || got.OutOfRange != tt.outOfRange {
+				t.Errorf("has_next = %v, has_prev = %v, out_of_range = %v, want %v, %v, %v",
+					got.HasNext, got.HasPrev, got.OutOfRange, tt.hasNext, tt.hasPrev, tt.outOfRange)
+			}
+		})
+	}
+
+	data, err := json.Marshal(NewUncountedPaginated(1, 10, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"total":null`) {
+		t.Errorf("uncounted pagination = %s, want a null total", data)
+	}
+}
+
+func TestListLogsWithoutCount(t *testing.T) {
+	s, _ := newTestLogService(t)
+	for _, name := range []string{"a.log", "b.log", "c.log", "d.log", "e.log"} {
+		uploadTestLog(t, s, UploadLogArgs{ClientID: "c1", UserID: "u1", FileName: name})
+	}
+
+	tests := []struct {
+		page    int
+		count   bool
+		logs    int
+		hasNext bool
+	}{
+		{1, false, 2, true},
+		{2, false, 2, true},
+		{3, false, 1, false},
+		{1, true, 2, true},
+		{3, true, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("page %d count=%v", tt.page, tt.count), func(t *testing.T) {
+			logs, paging, err := s.ListLogs(context.Background(), &ListLogsArgs{ClientId: "c1", Page: tt.page, PageSize: 2, Count: tt.count})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(logs) != tt.logs || paging.HasNext != tt.hasNext {
+				t.Errorf("got %d logs, has_next %v, want %d and %v", len(logs), paging.HasNext, tt.logs, tt.hasNext)
+			}
+			if (paging.Total != nil) != tt.count {
+				t.Errorf("total = %v, want counted %v", paging.Total, tt.count)
+			}
+		})
+	}
+}
+
+func TestNormalizePage(t *testing.T) {
+	testutil.SetConfig(t, "pagination.default", 20)
+	testutil.SetConfig(t, "pagination.max", 100)
+	testutil.SetConfig(t, "pagination.log.default", 200)
+	testutil.SetConfig(t, "pagination.log.max", 500)
+	testutil.SetConfig(t, "pagination.audit.max", 10)
+
+	tests := []struct {
+		name                   string
+		resource               string
+		page, pageSize         int
+		wantPage, wantPageSize int
+	}{
+		{"log default", pageResourceLog, 1, 0, 1, 200},
+		{"log cap", pageResourceLog, 1, 1000, 1, 500},
+		{"log under cap", pageResourceLog, 2, 300, 2, 300},
+		{"global fallback default", pageResourceSearch, 1, 0, 1, 20},
+		{"global fallback cap", pageResourceSearch, 1, 150, 1, 100},
+		{"default capped by resource max", pageResourceAudit, 1, 0, 1, 10},
+		{"page raised to 1", pageResourceLog, -3, 5, 1, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, pageSize := tt.page, tt.pageSize
+			normalizePage(tt.resource, &page, &pageSize)
+			if page != tt.wantPage || pageSize != tt.wantPageSize {
+				t.Errorf("page %d, page_size %d, want %d and %d", page, pageSize, tt.wantPage, tt.wantPageSize)
+			}
+		})
+	}
+}
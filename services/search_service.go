@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/zgsm-ai/client-manager/dao"
+	"github.com/zgsm-ai/client-manager/internal"
+)
+
+// Search sources
+const (
+	SearchSourceLog   = "log"
+	SearchSourceAudit = "audit"
+)
+
+// searchSources lists the searchable sources in their default order
+var searchSources = []string{SearchSourceLog, SearchSourceAudit}
+
+/**
+ * SearchService searches several resources at once
+ * @description
+ * - Queries every requested source concurrently
+ * - Merges the hits into one list, newest first
+ * - Limits the number of hits per source
+ */
+type SearchService struct {
+	logDAO   *dao.LogDAO
+	auditDAO *dao.AuditDAO
+	log      *logrus.Logger
+}
+
+type SearchArgs struct {
+	Q     string `form:"q"`
+	Types string `form:"types"`
+	Limit int    `form:"limit"`
+}
+
+/**
+ * SearchResult is one hit of a search
+ * @description
+ * - Source tells which resource Item belongs to
+ * - Time is the last update of a log or the creation of an audit entry
+ */
+type SearchResult struct {
+	Source string      `json:"source"`
+	ID     string      `json:"id"`
+	Time   time.Time   `json:"time"`
+	Item   interface{} `json:"item"`
+}
+
+/**
+ * SearchSourceInfo describes the hits of one source
+ * @description
+ * - Truncated is set when the source has more hits than the limit
+ */
+type SearchSourceInfo struct {
+	Count     int  `json:"count"`
+	Limit     int  `json:"limit"`
+	Truncated bool `json:"truncated"`
+}
+
+/**
+ * NewSearchService creates a new SearchService instance
+ * @param {dao.LogDAO} logDAO - Log data access object
+ * @param {dao.AuditDAO} auditDAO - Audit data access object
+ * @param {logrus.Logger} log - Logger instance
+ * @returns {*SearchService} New SearchService instance
+ */
+func NewSearchService(logDAO *dao.LogDAO, auditDAO *dao.AuditDAO, log *logrus.Logger) *SearchService {
+	return &SearchService{
+		logDAO:   logDAO,
+		auditDAO: auditDAO,
+		log:      log,
+	}
+}
+
+/**
+ * Search looks up a term in the requested sources
+ * @param {context.Context} ctx - Context for request cancellation
+ * @param {*SearchArgs} args - Term, comma separated sources (all when empty) and per-source limit
+ * @returns {[]SearchResult, map[string]SearchSourceInfo, error} Merged hits, per-source info and error if any
+ * @description
+ * - Runs one query per source concurrently, the first failure cancels the others
+ * - Limit defaults to pagination.search.default and is capped at pagination.search.max
+ * @throws
+ * - Validation errors for a missing term or an unknown source
+ * - Database query errors
+ */
+func (s *SearchService) Search(ctx context.Context, args *SearchArgs) ([]SearchResult, map[string]SearchSourceInfo, error) {
+	term := strings.TrimSpace(args.Q)
+	if term == "" {
+		return nil, nil, &ValidationError{Field: "q", Message: internal.NewMessage(internal.MsgFieldRequired, "field", "q"), Rule: RuleRequired}
+	}
+	sources, err := parseSearchTypes(args.Types)
+	if err != nil {
+		return nil, nil, err
+	}
+	page := 1
+	normalizePage(pageResourceSearch, &page, &args.Limit)
+
+	// Each source writes its own slot, no locking needed
+	hits := make([][]SearchResult, len(sources))
+	g, gctx := errgroup.WithContext(ctx)
+	for i, source := range sources {
+		i, source := i, source
+		g.Go(func() error {
+			var err error
+			hits[i], err = s.searchSource(gctx, source, term, args.Limit+1)
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		s.log.WithError(err).WithField("q", term).Error("Failed to search")
+		return nil, nil, err
+	}
+
+	results := []SearchResult{}
+	info := make(map[string]SearchSourceInfo, len(sources))
+	for i, source := range sources {
+		sourceHits := hits[i]
+		truncated := len(sourceHits) > args.Limit
+		if truncated {
+			sourceHits = sourceHits[:args.Limit]
+		}
+		info[source] = SearchSourceInfo{Count: len(sourceHits), Limit: args.Limit, Truncated: truncated}
+		results = append(results, sourceHits...)
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Time.After(results[j].Time) })
+	return results, info, nil
+}
+
+/**
+ * searchSource queries a single source
+ * @param {context.Context} ctx - Context cancelled when another source fails
+ * @param {string} source - One of the SearchSource* constants
+ * @param {string} term - Search term
+ * @param {int} limit - Maximum number of hits
+ * @returns {[]SearchResult, error} Hits of the source and error if any
+ */
+func (s *SearchService) searchSource(ctx context.Context, source, term string, limit int) ([]SearchResult, error) {
+	var hits []SearchResult
+	switch source {
+	case SearchSourceLog:
+		logs, err := s.logDAO.Search(ctx, term, limit)
+		if err != nil {
+			return nil, err
+		}
+		for i := range logs {
+			hits = append(hits, SearchResult{
+				Source: source,
+				ID:     strconv.FormatUint(uint64(logs[i].ID), 10),
+				Time:   logs[i].UpdatedAt,
+				Item:   logs[i],
+			})
+		}
+	case SearchSourceAudit:
+		entries, err := s.auditDAO.Search(ctx, term, limit)
+		if err != nil {
+			return nil, err
+		}
+		for i := range entries {
+			hits = append(hits, SearchResult{
+				Source: source,
+				ID:     strconv.FormatUint(uint64(entries[i].ID), 10),
+				Time:   entries[i].CreatedAt,
+				Item:   entries[i],
+			})
+		}
+	}
+	return hits, nil
+}
+
+/**
+ * parseSearchTypes parses the comma separated sources of a search
+ * @param {string} types - Requested sources, empty for all
+ * @returns {[]string, error} Distinct sources in request order and error if any
+ * @throws
+ * - Validation error for an unknown source
+ */
+func parseSearchTypes(types string) ([]string, error) {
+	if strings.TrimSpace(types) == "" {
+		return searchSources, nil
+	}
+	var sources []string
+	seen := map[string]bool{}
+	for _, source := range strings.Split(types, ",") {
+		source = strings.ToLower(strings.TrimSpace(source))
+		if source == "" || seen[source] {
+			continue
+		}
+		known := false
+		for _, s := range searchSources {
+			known = known || s == source
+		}
+		if !known {
+			return nil, &ValidationError{
+				Field:   "types",
+				Message: internal.NewMessage(internal.MsgSearchSourceUnknown, "allowed", strings.Join(searchSources, ", ")),
+				Rule:    RuleEnum,
+			}
+		}
+		seen[source] = true
+		sources = append(sources, source)
+	}
+	if len(sources) == 0 {
+		return searchSources, nil
+	}
+	return sources, nil
+}
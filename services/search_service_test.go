@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zgsm-ai/client-manager/dao"
+	"github.com/zgsm-ai/client-manager/internal/testutil"
+	"github.com/zgsm-ai/client-manager/models"
+)
+
+func TestSearchMergesSources(t *testing.T) {
+	db := testutil.NewDB(t, nil, &models.Log{}, &models.AuditEntry{})
+	log := testutil.NewLogger()
+	s := NewSearchService(dao.NewLogDAO(db, log), dao.NewAuditDAO(db, log), log)
+
+	// Hits of both sources interleave in time
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	logs := []models.Log{
+		{ClientID: "acme", FileName: "1.log", UpdatedAt: base.Add(1 * time.Hour)},
+		{ClientID: "acme", FileName: "2.log", UpdatedAt: base.Add(3 * time.Hour)},
+		{ClientID: "other", FileName: "3.log", UpdatedAt: base.Add(5 * time.Hour)},
+	}
+	entries := []models.AuditEntry{
+		{Actor: "acme-admin", Action: "create", Resource: "log", CreatedAt: base.Add(2 * time.Hour)},
+		{Actor: "acme-admin", Action: "delete", Resource: "log", CreatedAt: base.Add(4 * time.Hour)},
+	}
+	if err := db.Create(&logs).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&entries).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		args      SearchArgs
+		want      []string
+		truncated map[string]bool
+		field     string
+		rule      string
+	}{
+		{"all sources newest first", SearchArgs{Q: "acme"}, []string{"audit", "log", "audit", "log"},
+			map[string]bool{"log": false, "audit": false}, "", ""},
+		{"single source", SearchArgs{Q: "acme", Types: "log"}, []string{"log", "log"},
+			map[string]bool{"log": false}, "", ""},
+		{"repeated and mixed case sources", SearchArgs{Q: "acme", Types: "AUDIT, log ,audit"}, []string{"audit", "log", "audit", "log"},
+			map[string]bool{"log": false, "audit": false}, "", ""},
+		{"limit per source", SearchArgs{Q: "acme", Limit: 1}, []string{"audit", "log"},
+			map[string]bool{"log": true, "audit": true}, "", ""},
+		{"missing term", SearchArgs{Q: "  "}, nil, nil, "q", RuleRequired},
+		{"unknown source", SearchArgs{Q: "acme", Types: "log,users"}, nil, nil, "types", RuleEnum},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			results, info, err := s.Search(context.Background(), &args)
+			if tt.rule != "" {
+				assertRule(t, err, tt.field, tt.rule)
+				return
+			}
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			var got []string
+			for i, r := range results {
+				got = append(got, r.Source)
+				if i > 0 && r.Time.After(results[i-1].Time) {
+					t.Errorf("result %d at %v is newer than the one before it", i, r.Time)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sources = %v, want %v", got, tt.want)
+			}
+			if len(info) != len(tt.truncated) {
+				t.Errorf("info = %v, want %d sources", info, len(tt.truncated))
+			}
+			for source, truncated := range tt.truncated {
+				if info[source].Truncated != truncated {
+					t.Errorf("%s truncated = %v, want %v", source, info[source].Truncated, truncated)
+				}
+			}
+		})
+	}
+}